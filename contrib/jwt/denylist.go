@@ -0,0 +1,74 @@
+package gtoken_jwt
+
+import (
+	"context"
+	"github.com/goflyfox/gtoken/v2/gtoken"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/util/gconv"
+	"time"
+)
+
+const (
+	CacheKeyDenyToken = "deny:token:" // 注销token，key为jti
+	CacheKeyDenyUser  = "deny:user:"  // 注销用户，key为userKey
+
+	KeyRevokeTime = "revokeTime" // 注销时间（纳秒）
+)
+
+// revokeToken 注销单个token，记录保留到token过期，含时钟偏差
+func (m *JwtToken) revokeToken(ctx context.Context, jwtClaims *JwtClaims) error {
+	if jwtClaims.ID == "" {
+		return gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+	}
+	var expireTime int64
+	if jwtClaims.ExpiresAt != nil {
+		expireTime = jwtClaims.ExpiresAt.UnixMilli() + m.JwtOptions.Leeway
+	}
+	return m.cache.Set(ctx, CacheKeyDenyToken+jwtClaims.ID, g.Map{
+		gtoken.KeyUserKey:    jwtClaims.UserKey,
+		gtoken.KeyExpireTime: expireTime,
+	})
+}
+
+// revokeUser 注销用户，此时间前签发的token全部失效
+func (m *JwtToken) revokeUser(ctx context.Context, userKey string) error {
+	return m.cache.Set(ctx, CacheKeyDenyUser+userKey, g.Map{
+		gtoken.KeyUserKey: userKey,
+		KeyRevokeTime:     time.Now().UnixNano(),
+	})
+}
+
+// isRevoked 判断token是否已注销
+func (m *JwtToken) isRevoked(ctx context.Context, jwtClaims *JwtClaims) (bool, error) {
+	if jwtClaims.ID != "" {
+		cacheValue, err := m.cache.Get(ctx, CacheKeyDenyToken+jwtClaims.ID)
+		if err != nil {
+			return false, err
+		}
+		if cacheValue != nil && time.Now().UnixMilli() <= gconv.Int64(cacheValue[gtoken.KeyExpireTime]) {
+			return true, nil
+		}
+	}
+	cacheValue, err := m.cache.Get(ctx, CacheKeyDenyUser+jwtClaims.UserKey)
+	if err != nil {
+		return false, err
+	}
+	if cacheValue == nil {
+		return false, nil
+	}
+	revokeTime := gconv.Int64(cacheValue[KeyRevokeTime])
+	if jwtClaims.Now != 0 {
+		return jwtClaims.Now <= revokeTime, nil
+	}
+	// 自定义声明token没有Now字段，使用纳秒签发时间iat_ns
+	if jwtClaims.IssuedNano != 0 {
+		return jwtClaims.IssuedNano <= revokeTime, nil
+	}
+	// 没有iat_ns的token使用签发时间iat（秒），与注销同一秒内签发的token同样失效
+	if jwtClaims.IssuedAt == nil {
+		return true, nil
+	}
+	return jwtClaims.IssuedAt.Unix() <= revokeTime/int64(time.Second), nil
+}
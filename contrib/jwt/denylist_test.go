@@ -0,0 +1,137 @@
+package gtoken_jwt_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/goflyfox/gtoken-jwt/v2"
+	"github.com/goflyfox/gtoken/v2/gtoken"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDenylist(t *testing.T) {
+	ctx := gctx.New()
+	userKey := "testUser"
+
+	// 未启用注销名单，不支持销毁单个token
+	{
+		gfToken := gtoken_jwt.New(gtoken.Options{})
+		token, err := gfToken.Generate(ctx, userKey, nil)
+		assert.NoError(t, err)
+		assert.NoError(t, gfToken.Destroy(ctx, userKey))
+		_, err = gfToken.Validate(ctx, token)
+		assert.NoError(t, err)
+		err = gfToken.DestroyToken(ctx, token)
+		assert.Equal(t, gcode.CodeNotSupported, gerror.Code(err))
+	}
+
+	for _, options := range []gtoken_jwt.Options{
+		{Options: gtoken.Options{Timeout: 1000}, Denylist: true},
+		{Options: gtoken.Options{Timeout: 1000, CacheMode: gtoken.CacheModeFile, CachePreKey: "GTokenJwtDeny:"}, Denylist: true},
+		{Options: gtoken.Options{Timeout: 1000}, Denylist: true, Cache: gtoken.NewDefaultCache(gtoken.CacheModeCache, "GTokenJwtCustom:", 1000)},
+	} {
+		gfToken := gtoken_jwt.NewWithOptions(options)
+		token1, err := gfToken.Generate(ctx, userKey, nil)
+		assert.NoError(t, err)
+		token2, err := gfToken.Generate(ctx, userKey, nil)
+		assert.NoError(t, err)
+
+		// 销毁单个token
+		assert.NoError(t, gfToken.DestroyToken(ctx, token1))
+		_, err = gfToken.Validate(ctx, token1)
+		assert.Equal(t, gtoken.CodeTokenRevoked, gerror.Code(err))
+		_, _, err = gfToken.ParseToken(ctx, token1)
+		assert.Equal(t, gtoken.CodeTokenRevoked, gerror.Code(err))
+		_, err = gfToken.Validate(ctx, token2)
+		assert.NoError(t, err)
+
+		// 销毁用户，之前签发的token全部失效，之后签发的token有效
+		assert.NoError(t, gfToken.Destroy(ctx, userKey))
+		_, err = gfToken.Validate(ctx, token2)
+		assert.Equal(t, gtoken.CodeTokenRevoked, gerror.Code(err))
+		token3, err := gfToken.Generate(ctx, userKey, nil)
+		assert.NoError(t, err)
+		_, err = gfToken.Validate(ctx, token3)
+		assert.NoError(t, err)
+		_, err = gfToken.Validate(ctx, token1)
+		assert.Error(t, err)
+	}
+
+	// 注销记录随token过期
+	{
+		cache := gtoken.NewDefaultCache(gtoken.CacheModeCache, "GTokenJwtExpire:", 1500)
+		gfToken := gtoken_jwt.NewWithOptions(gtoken_jwt.Options{
+			Options:  gtoken.Options{Timeout: 1500},
+			Denylist: true,
+			Cache:    cache,
+		})
+		token, err := gfToken.Generate(ctx, userKey, nil)
+		assert.NoError(t, err)
+		assert.NoError(t, gfToken.DestroyToken(ctx, token))
+		size, err := cache.Cache.Size(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, size)
+		time.Sleep(1600 * time.Millisecond)
+		size, err = cache.Cache.Size(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, size)
+	}
+
+	// 时钟偏差内过期的token，注销后仍然无效
+	{
+		gfToken := gtoken_jwt.NewWithOptions(gtoken_jwt.Options{
+			Options:  gtoken.Options{Timeout: 200, CachePreKey: "GTokenJwtLeeway:"},
+			Denylist: true,
+			Leeway:   2000,
+		})
+		token1, err := gfToken.Generate(ctx, userKey, nil)
+		assert.NoError(t, err)
+		token2, err := gfToken.Generate(ctx, userKey, nil)
+		assert.NoError(t, err)
+		assert.NoError(t, gfToken.DestroyToken(ctx, token1))
+		time.Sleep(400 * time.Millisecond)
+		_, err = gfToken.Validate(ctx, token2)
+		assert.NoError(t, err)
+		_, err = gfToken.Validate(ctx, token1)
+		assert.Equal(t, gtoken.CodeTokenRevoked, gerror.Code(err))
+		assert.NoError(t, gfToken.Destroy(ctx, userKey))
+		_, err = gfToken.Validate(ctx, token2)
+		assert.Equal(t, gtoken.CodeTokenRevoked, gerror.Code(err))
+	}
+}
+
+func TestDenylistCacheEncryptKey(t *testing.T) {
+	ctx := gctx.New()
+	encryptKey := []byte("12345678912345678912345678912345")
+
+	// 缓存数据加密key长度错误或与签名密钥相同时创建失败
+	assert.Panics(t, func() {
+		gtoken_jwt.NewWithOptions(gtoken_jwt.Options{Options: gtoken.Options{CacheEncryptKey: []byte("short")}, Denylist: true})
+	})
+	assert.Panics(t, func() {
+		gtoken_jwt.NewWithOptions(gtoken_jwt.Options{Options: gtoken.Options{EncryptKey: encryptKey, CacheEncryptKey: encryptKey}, Denylist: true})
+	})
+	assert.Panics(t, func() {
+		gtoken_jwt.NewWithOptions(gtoken_jwt.Options{Options: gtoken.Options{CacheEncryptKey: []byte(gtoken.DefaultEncryptKey)}, Track: true})
+	})
+	assert.Panics(t, func() {
+		gtoken_jwt.NewWithOptions(gtoken_jwt.Options{
+			Options:  gtoken.Options{CacheEncryptKey: encryptKey},
+			Keys:     []gtoken_jwt.Key{{Kid: "k1", EncryptKey: encryptKey}},
+			Denylist: true,
+		})
+	})
+
+	gfToken := gtoken_jwt.NewWithOptions(gtoken_jwt.Options{
+		Options:  gtoken.Options{EncryptKey: encryptKey, CacheEncryptKey: []byte("abcdefghijklmnopabcdefghijklmnop")},
+		Denylist: true,
+	})
+	token, err := gfToken.Generate(ctx, "testUser", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, gfToken.DestroyToken(ctx, token))
+	_, err = gfToken.Validate(ctx, token)
+	assert.Error(t, err)
+}
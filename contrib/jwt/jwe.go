@@ -0,0 +1,187 @@
+package gtoken_jwt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/json"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/os/gfile"
+	"github.com/gogf/gf/v2/text/gstr"
+	"github.com/golang-jwt/jwt/v5"
+	"hash"
+)
+
+const (
+	JweAlgDir         = "dir"          // 直接使用共享密钥加密
+	JweAlgRsaOaep     = "RSA-OAEP"     // RSA-OAEP（SHA-1）加密内容密钥
+	JweAlgRsaOaep256  = "RSA-OAEP-256" // RSA-OAEP（SHA-256）加密内容密钥
+	JweEncA256GCM     = "A256GCM"
+	JweContentKeySize = 32
+
+	MsgErrJweKey     = "jwe key not configured"
+	MsgErrJweDecrypt = "jwe decrypt fail"
+)
+
+// jweHeader JWE头部，参考RFC 7516
+type jweHeader struct {
+	Alg string `json:"alg"`
+	Enc string `json:"enc"`
+	Cty string `json:"cty,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// jweEncrypter JWE加解密，签名后的jwt作为内容加密（Nested JWT）
+type jweEncrypter struct {
+	alg        string
+	kid        string
+	key        []byte          // dir模式内容密钥
+	publicKey  *rsa.PublicKey  // RSA-OAEP加密公钥
+	privateKey *rsa.PrivateKey // RSA-OAEP解密私钥
+}
+
+// newJweEncrypter 根据配置创建JWE加解密，未配置JweAlgorithm时返回nil
+func newJweEncrypter(options Options) (encrypter *jweEncrypter, err error) {
+	if options.JweAlgorithm == "" {
+		return nil, nil
+	}
+	encrypter = &jweEncrypter{alg: options.JweAlgorithm, kid: options.JweKid}
+	switch options.JweAlgorithm {
+	case JweAlgDir:
+		if len(options.JweKey) != JweContentKeySize {
+			return nil, gerror.NewCodef(gcode.CodeInvalidConfiguration, "%s: dir key must be %d bytes", MsgErrJweKey, JweContentKeySize)
+		}
+		encrypter.key = options.JweKey
+	case JweAlgRsaOaep, JweAlgRsaOaep256:
+		privatePem := options.JwePrivateKey
+		if len(privatePem) == 0 && options.JwePrivateKeyFile != "" {
+			privatePem = gfile.GetBytes(options.JwePrivateKeyFile)
+		}
+		publicPem := options.JwePublicKey
+		if len(publicPem) == 0 && options.JwePublicKeyFile != "" {
+			publicPem = gfile.GetBytes(options.JwePublicKeyFile)
+		}
+		if len(privatePem) > 0 {
+			key, err := parsePrivateKey(jwt.SigningMethodRS256, privatePem)
+			if err != nil {
+				return nil, err
+			}
+			encrypter.privateKey = key.(*rsa.PrivateKey)
+			encrypter.publicKey = &encrypter.privateKey.PublicKey
+		}
+		if len(publicPem) > 0 {
+			key, err := parsePublicKey(jwt.SigningMethodRS256, publicPem)
+			if err != nil {
+				return nil, err
+			}
+			encrypter.publicKey = key.(*rsa.PublicKey)
+		}
+		if encrypter.publicKey == nil {
+			return nil, gerror.NewCode(gcode.CodeInvalidConfiguration, MsgErrJweKey)
+		}
+	default:
+		return nil, gerror.NewCodef(gcode.CodeInvalidConfiguration, "unsupported jwe algorithm: %s", options.JweAlgorithm)
+	}
+	return
+}
+
+// encrypt 加密签名后的jwt，返回JWE Compact格式token
+func (e *jweEncrypter) encrypt(token string) (string, error) {
+	header, err := json.Marshal(jweHeader{Alg: e.alg, Enc: JweEncA256GCM, Cty: "JWT", Kid: e.kid})
+	if err != nil {
+		return "", err
+	}
+	var (
+		protected = encodeSegment(header)
+		cek       = e.key
+		wrapped   []byte
+	)
+	if e.alg != JweAlgDir {
+		cek = make([]byte, JweContentKeySize)
+		if _, err = rand.Read(cek); err != nil {
+			return "", err
+		}
+		if wrapped, err = rsa.EncryptOAEP(e.hash(), rand.Reader, e.publicKey, cek, nil); err != nil {
+			return "", err
+		}
+	}
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return "", err
+	}
+	iv := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(iv); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nil, iv, []byte(token), []byte(protected))
+	ciphertext, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+	return protected + "." + encodeSegment(wrapped) + "." + encodeSegment(iv) + "." +
+		encodeSegment(ciphertext) + "." + encodeSegment(tag), nil
+}
+
+// decrypt 解密JWE Compact格式token，返回签名后的jwt
+// 仅接受配置的alg及A256GCM，拒绝未加密的token
+func (e *jweEncrypter) decrypt(token string) (string, error) {
+	parts := gstr.Split(token, ".")
+	if len(parts) != 5 {
+		return "", gerror.NewCode(gcode.CodeInvalidParameter, MsgErrJweDecrypt)
+	}
+	headerBytes, err := decodeSegment(parts[0])
+	if err != nil {
+		return "", gerror.NewCode(gcode.CodeInvalidParameter, MsgErrJweDecrypt)
+	}
+	var header jweHeader
+	if err = json.Unmarshal(headerBytes, &header); err != nil || header.Alg != e.alg || header.Enc != JweEncA256GCM {
+		return "", gerror.NewCode(gcode.CodeInvalidParameter, MsgErrJweDecrypt)
+	}
+	segments := make([][]byte, 4)
+	for i := range segments {
+		if segments[i], err = decodeSegment(parts[i+1]); err != nil {
+			return "", gerror.NewCode(gcode.CodeInvalidParameter, MsgErrJweDecrypt)
+		}
+	}
+	wrapped, iv, ciphertext, tag := segments[0], segments[1], segments[2], segments[3]
+
+	cek := e.key
+	if e.alg != JweAlgDir {
+		if e.privateKey == nil {
+			return "", gerror.NewCode(gcode.CodeInvalidConfiguration, MsgErrJweKey)
+		}
+		if cek, err = rsa.DecryptOAEP(e.hash(), nil, e.privateKey, wrapped, nil); err != nil {
+			return "", gerror.NewCode(gcode.CodeInvalidParameter, MsgErrJweDecrypt)
+		}
+	} else if len(wrapped) != 0 {
+		return "", gerror.NewCode(gcode.CodeInvalidParameter, MsgErrJweDecrypt)
+	}
+	gcm, err := newGCM(cek)
+	if err != nil || len(iv) != gcm.NonceSize() || len(tag) != gcm.Overhead() {
+		return "", gerror.NewCode(gcode.CodeInvalidParameter, MsgErrJweDecrypt)
+	}
+	plaintext, err := gcm.Open(nil, iv, append(ciphertext, tag...), []byte(parts[0]))
+	if err != nil {
+		return "", gerror.NewCode(gcode.CodeInvalidParameter, MsgErrJweDecrypt)
+	}
+	return string(plaintext), nil
+}
+
+func (e *jweEncrypter) hash() hash.Hash {
+	if e.alg == JweAlgRsaOaep {
+		return sha1.New()
+	}
+	return sha256.New()
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != JweContentKeySize {
+		return nil, gerror.NewCode(gcode.CodeInvalidParameter, MsgErrJweDecrypt)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
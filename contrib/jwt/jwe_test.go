@@ -0,0 +1,105 @@
+package gtoken_jwt_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/goflyfox/gtoken-jwt/v2"
+	"github.com/goflyfox/gtoken/v2/gtoken"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJwe(t *testing.T) {
+	ctx := gctx.New()
+	userKey := "testUser"
+	data := g.Map{"idCard": "110101199001011234"}
+	jweKey := []byte("12345678901234567890123456789012")
+
+	gfToken := gtoken_jwt.NewWithOptions(gtoken_jwt.Options{
+		JweAlgorithm: gtoken_jwt.JweAlgDir,
+		JweKey:       jweKey,
+	})
+	token, err := gfToken.Generate(ctx, userKey, data)
+	assert.NoError(t, err)
+	parts := strings.Split(token, ".")
+	assert.Len(t, parts, 5)
+	assert.Empty(t, parts[1])
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	assert.NoError(t, err)
+	assert.Contains(t, string(header), `"alg":"dir"`)
+	assert.Contains(t, string(header), `"enc":"A256GCM"`)
+	for _, part := range parts {
+		decoded, _ := base64.RawURLEncoding.DecodeString(part)
+		assert.NotContains(t, string(decoded), "110101199001011234")
+	}
+
+	userKey2, data2, err := gfToken.ParseToken(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, userKey, userKey2)
+	assert.Equal(t, data, data2)
+
+	// 篡改密文
+	tampered := []byte(parts[3])
+	if tampered[0] == 'A' {
+		tampered[0] = 'B'
+	} else {
+		tampered[0] = 'A'
+	}
+	parts[3] = string(tampered)
+	_, err = gfToken.Validate(ctx, strings.Join(parts, "."))
+	assert.Error(t, err)
+
+	// 启用JWE后拒绝未加密token
+	plainToken, err := gtoken_jwt.New(gtoken.Options{}).Generate(ctx, userKey, data)
+	assert.NoError(t, err)
+	_, err = gfToken.Validate(ctx, plainToken)
+	assert.Error(t, err)
+
+	// 密钥不一致
+	other := gtoken_jwt.NewWithOptions(gtoken_jwt.Options{
+		JweAlgorithm: gtoken_jwt.JweAlgDir,
+		JweKey:       []byte("abcdefghijabcdefghijabcdefghij12"),
+	})
+	_, err = other.Validate(ctx, token)
+	assert.Error(t, err)
+
+	assert.Panics(t, func() {
+		gtoken_jwt.NewWithOptions(gtoken_jwt.Options{JweAlgorithm: gtoken_jwt.JweAlgDir, JweKey: []byte("short")})
+	})
+}
+
+func TestJweRsaOaep(t *testing.T) {
+	ctx := gctx.New()
+	userKey := "testUser"
+	data := g.Map{"a": "1"}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	privatePem, publicPem := pemKeys(t, rsaKey, &rsaKey.PublicKey)
+
+	for _, alg := range []string{gtoken_jwt.JweAlgRsaOaep, gtoken_jwt.JweAlgRsaOaep256} {
+		// 生成方仅持有加密公钥
+		issuer := gtoken_jwt.NewWithOptions(gtoken_jwt.Options{
+			JweAlgorithm: alg,
+			JwePublicKey: publicPem,
+		})
+		receiver := gtoken_jwt.NewWithOptions(gtoken_jwt.Options{
+			JweAlgorithm:  alg,
+			JwePrivateKey: privatePem,
+		})
+		token, err := issuer.Generate(ctx, userKey, data)
+		assert.NoError(t, err)
+		assert.Len(t, strings.Split(token, "."), 5)
+
+		userKey2, data2, err := receiver.ParseToken(ctx, token)
+		assert.NoError(t, err, alg)
+		assert.Equal(t, userKey, userKey2)
+		assert.Equal(t, data, data2)
+		_, err = issuer.Validate(ctx, token)
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,158 @@
+package gtoken_jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"sort"
+)
+
+const (
+	DefaultJwksMaxAge = 300 // JWKS缓存时间（秒）
+
+	MsgErrJwkType    = "unsupported jwk type"
+	MsgErrJwkInvalid = "invalid jwk"
+)
+
+// JSONWebKey JWK公钥，参考RFC 7517
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // EC/OKP曲线
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JSONWebKeySet JWKS文档
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// Jwks 获取全部非对称验证公钥，HS系列共享密钥不发布
+func (m *JwtToken) Jwks() JSONWebKeySet {
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, key := range m.keys.list() {
+		jwk, ok := publicJwk(key)
+		if !ok {
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].Kid < set.Keys[j].Kid
+	})
+	return set
+}
+
+// JwksHandler 发布JWKS文档，例如：s.BindHandler("/.well-known/jwks.json", gfToken.JwksHandler)
+func (m *JwtToken) JwksHandler(r *ghttp.Request) {
+	r.Response.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", DefaultJwksMaxAge))
+	r.Response.WriteJson(m.Jwks())
+}
+
+// publicJwk 验证公钥转换为JWK
+func publicJwk(key *signingKey) (jwk JSONWebKey, ok bool) {
+	jwk = JSONWebKey{Kid: key.kid, Use: "sig", Alg: key.method.Alg()}
+	switch publicKey := key.verifyKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encodeSegment(publicKey.N.Bytes())
+		jwk.E = encodeSegment(big.NewInt(int64(publicKey.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = publicKey.Curve.Params().Name
+		jwk.X = encodeSegment(publicKey.X.FillBytes(make([]byte, size)))
+		jwk.Y = encodeSegment(publicKey.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encodeSegment(publicKey)
+	default:
+		return jwk, false
+	}
+	return jwk, true
+}
+
+// PublicKey JWK转换为验证公钥，支持RSA、EC（P-256/384/521）、OKP（Ed25519）
+func (k JSONWebKey) PublicKey() (key any, err error) {
+	switch k.Kty {
+	case "RSA":
+		n, e1 := decodeSegment(k.N)
+		e, e2 := decodeSegment(k.E)
+		if e1 != nil || e2 != nil || len(n) == 0 || len(e) == 0 {
+			break
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, gerror.NewCodef(gcode.CodeNotSupported, "%s: %s", MsgErrJwkType, k.Crv)
+		}
+		x, e1 := decodeSegment(k.X)
+		y, e2 := decodeSegment(k.Y)
+		if e1 != nil || e2 != nil {
+			break
+		}
+		publicKey := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(publicKey.X, publicKey.Y) {
+			break
+		}
+		return publicKey, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, gerror.NewCodef(gcode.CodeNotSupported, "%s: %s", MsgErrJwkType, k.Crv)
+		}
+		x, e := decodeSegment(k.X)
+		if e != nil || len(x) != ed25519.PublicKeySize {
+			break
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, gerror.NewCodef(gcode.CodeNotSupported, "%s: %s", MsgErrJwkType, k.Kty)
+	}
+	return nil, gerror.NewCodef(gcode.CodeInvalidParameter, "%s: %s", MsgErrJwkInvalid, k.Kid)
+}
+
+// supports 判断JWK是否可用于验证签名算法alg
+func (k JSONWebKey) supports(alg string) bool {
+	if k.Alg != "" {
+		return k.Alg == alg
+	}
+	switch jwt.GetSigningMethod(alg).(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		return k.Kty == "RSA"
+	case *jwt.SigningMethodECDSA:
+		return k.Kty == "EC"
+	case *jwt.SigningMethodEd25519:
+		return k.Kty == "OKP"
+	}
+	return false
+}
+
+func encodeSegment(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeSegment(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
@@ -0,0 +1,132 @@
+package gtoken_jwt_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/goflyfox/gtoken-jwt/v2"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/util/guid"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyRotation(t *testing.T) {
+	ctx := gctx.New()
+	userKey := "testUser"
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	rsaPem, _ := pemKeys(t, rsaKey, &rsaKey.PublicKey)
+	ecPem, _ := pemKeys(t, ecKey, &ecKey.PublicKey)
+	edPem, _ := pemKeys(t, edKey, edPublic)
+
+	gfToken := gtoken_jwt.NewWithOptions(gtoken_jwt.Options{
+		SigningMethod: "RS256",
+		Keys: []gtoken_jwt.Key{
+			{Kid: "k1", PrivateKey: rsaPem},
+			{Kid: "k2", SigningMethod: "ES256", PrivateKey: ecPem},
+		},
+	})
+	token1, err := gfToken.Generate(ctx, userKey, nil)
+	assert.NoError(t, err)
+	header, _, err := jwt.NewParser().ParseUnverified(token1, &jwt.RegisteredClaims{})
+	assert.NoError(t, err)
+	assert.Equal(t, "k1", header.Header["kid"])
+
+	// 轮换签名密钥，旧token仍可验证
+	assert.NoError(t, gfToken.SetActiveKey("k2"))
+	token2, err := gfToken.Generate(ctx, userKey, nil)
+	assert.NoError(t, err)
+	header, _, err = jwt.NewParser().ParseUnverified(token2, &jwt.RegisteredClaims{})
+	assert.NoError(t, err)
+	assert.Equal(t, "k2", header.Header["kid"])
+	assert.Equal(t, "ES256", header.Method.Alg())
+	for _, token := range []string{token1, token2} {
+		u, err := gfToken.Validate(ctx, token)
+		assert.NoError(t, err)
+		assert.Equal(t, userKey, u)
+	}
+
+	// 运行时加入密钥
+	assert.NoError(t, gfToken.AddKey(gtoken_jwt.Key{Kid: "k3", SigningMethod: "EdDSA", PrivateKey: edPem}))
+	assert.Error(t, gfToken.AddKey(gtoken_jwt.Key{SigningMethod: "EdDSA", PrivateKey: edPem}))
+	assert.NoError(t, gfToken.SetActiveKey("k3"))
+	assert.Error(t, gfToken.SetActiveKey("notExist"))
+	token3, err := gfToken.Generate(ctx, userKey, nil)
+	assert.NoError(t, err)
+
+	// 发布JWKS，使用发布的公钥验证token
+	jwks := gfToken.Jwks()
+	assert.Len(t, jwks.Keys, 3)
+	jwkMap := make(map[string]gtoken_jwt.JSONWebKey)
+	for _, jwk := range jwks.Keys {
+		jwkMap[jwk.Kid] = jwk
+	}
+	assert.Equal(t, "RSA", jwkMap["k1"].Kty)
+	assert.Equal(t, "EC", jwkMap["k2"].Kty)
+	assert.Equal(t, "P-256", jwkMap["k2"].Crv)
+	assert.Equal(t, "OKP", jwkMap["k3"].Kty)
+	n, err := base64.RawURLEncoding.DecodeString(jwkMap["k1"].N)
+	assert.NoError(t, err)
+	publicKey := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537}
+	_, err = jwt.Parse(token1, func(token *jwt.Token) (interface{}, error) { return publicKey, nil })
+	assert.NoError(t, err)
+	x, err := base64.RawURLEncoding.DecodeString(jwkMap["k3"].X)
+	assert.NoError(t, err)
+	_, err = jwt.Parse(token3, func(token *jwt.Token) (interface{}, error) { return ed25519.PublicKey(x), nil })
+	assert.NoError(t, err)
+
+	// 移除密钥后，旧token验证失败；不能移除签名密钥
+	assert.NoError(t, gfToken.RemoveKey("k1"))
+	_, err = gfToken.Validate(ctx, token1)
+	assert.Error(t, err)
+	assert.Error(t, gfToken.RemoveKey("k3"))
+	_, err = gfToken.Validate(ctx, token2)
+	assert.NoError(t, err)
+}
+
+func TestJwksHandler(t *testing.T) {
+	ctx := gctx.New()
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	edPem, _ := pemKeys(t, edKey, edPublic)
+	gfToken := gtoken_jwt.NewWithOptions(gtoken_jwt.Options{
+		SigningMethod: "EdDSA",
+		Keys:          []gtoken_jwt.Key{{Kid: "k1", PrivateKey: edPem}},
+	})
+	// HS256共享密钥不发布
+	assert.Empty(t, gtoken_jwt.NewWithOptions(gtoken_jwt.Options{}).Jwks().Keys)
+
+	s := g.Server(guid.S())
+	s.BindHandler("/.well-known/jwks.json", gfToken.JwksHandler)
+	s.SetPort(0)
+	s.SetDumpRouterMap(false)
+	assert.NoError(t, s.Start())
+	defer s.Shutdown()
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/.well-known/jwks.json", s.GetListenedPort())
+	resp, err := g.Client().Get(ctx, url)
+	assert.NoError(t, err)
+	defer resp.Close()
+	assert.Contains(t, resp.Header.Get("Cache-Control"), "max-age")
+	var jwks gtoken_jwt.JSONWebKeySet
+	assert.NoError(t, json.Unmarshal(resp.ReadAll(), &jwks))
+	assert.Len(t, jwks.Keys, 1)
+	assert.Equal(t, "k1", jwks.Keys[0].Kid)
+	assert.Equal(t, "EdDSA", jwks.Keys[0].Alg)
+	assert.Equal(t, "sig", jwks.Keys[0].Use)
+}
@@ -0,0 +1,243 @@
+package gtoken_jwt
+
+import (
+	"crypto"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/os/gfile"
+	"github.com/golang-jwt/jwt/v5"
+	"sync"
+)
+
+const (
+	MsgErrSigningMethod = "unsupported signing method"
+	MsgErrPrivateKey    = "private key not configured"
+	MsgErrPublicKey     = "public key not configured"
+	MsgErrKeyNotFound   = "signing key not found"
+	MsgErrKidEmpty      = "kid is empty"
+)
+
+// Key 签名密钥，通过Kid区分，用于密钥轮换
+type Key struct {
+	Kid            string // 密钥ID，写入token头部kid
+	SigningMethod  string // 签名算法，为空时使用Options.SigningMethod
+	EncryptKey     []byte // HS系列共享密钥，为空时使用Options.EncryptKey
+	PrivateKey     []byte // PEM格式私钥，为空时该密钥仅用于验证
+	PublicKey      []byte // PEM格式公钥，为空时从私钥获取
+	PrivateKeyFile string // PEM格式私钥文件路径
+	PublicKeyFile  string // PEM格式公钥文件路径
+}
+
+// signingKey 已加载的密钥
+type signingKey struct {
+	kid       string
+	method    jwt.SigningMethod
+	signKey   any // 签名密钥
+	verifyKey any // 验证密钥
+}
+
+// keySet 密钥集合，使用active密钥签名，使用kid对应密钥验证
+type keySet struct {
+	mu     sync.RWMutex
+	keys   map[string]*signingKey
+	active string
+}
+
+// newKeySet 根据配置加载密钥集合
+// 未配置Keys时使用Options中的密钥，kid为空，兼容单密钥配置
+func newKeySet(options Options) (set *keySet, err error) {
+	set = &keySet{keys: make(map[string]*signingKey)}
+	keys := options.Keys
+	if len(keys) == 0 {
+		keys = []Key{{
+			PrivateKey:     options.PrivateKey,
+			PublicKey:      options.PublicKey,
+			PrivateKeyFile: options.PrivateKeyFile,
+			PublicKeyFile:  options.PublicKeyFile,
+		}}
+	}
+	for _, key := range keys {
+		if err = set.add(options, key); err != nil {
+			return
+		}
+	}
+
+	set.active = options.ActiveKid
+	if set.active == "" {
+		// 默认使用第一个可签名的密钥
+		for _, key := range keys {
+			if set.keys[key.Kid].signKey != nil {
+				set.active = key.Kid
+				break
+			}
+		}
+	}
+	if _, ok := set.keys[set.active]; !ok && options.ActiveKid != "" {
+		err = gerror.NewCodef(gcode.CodeInvalidConfiguration, "%s: %s", MsgErrKeyNotFound, set.active)
+	}
+	return
+}
+
+// add 加载并加入密钥，kid相同时覆盖
+func (s *keySet) add(options Options, key Key) error {
+	if key.SigningMethod == "" {
+		key.SigningMethod = options.SigningMethod
+	}
+	if len(key.EncryptKey) == 0 {
+		key.EncryptKey = options.EncryptKey
+	}
+	loaded, err := loadKey(key)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[key.Kid] = loaded
+	return nil
+}
+
+// remove 移除密钥，不能移除当前签名密钥
+func (s *keySet) remove(kid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if kid == s.active {
+		return gerror.NewCodef(gcode.CodeInvalidOperation, "active key can not be removed: %s", kid)
+	}
+	delete(s.keys, kid)
+	return nil
+}
+
+// setActive 设置签名密钥
+func (s *keySet) setActive(kid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.keys[kid]
+	if !ok {
+		return gerror.NewCodef(gcode.CodeInvalidParameter, "%s: %s", MsgErrKeyNotFound, kid)
+	}
+	if key.signKey == nil {
+		return gerror.NewCode(gcode.CodeInvalidParameter, MsgErrPrivateKey)
+	}
+	s.active = kid
+	return nil
+}
+
+// signer 获取当前签名密钥，未配置时返回nil
+func (s *keySet) signer() *signingKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keys[s.active]
+}
+
+// get 通过kid获取密钥
+func (s *keySet) get(kid string) (*signingKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// list 获取全部密钥
+func (s *keySet) list() []*signingKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]*signingKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// methods 获取全部密钥的签名算法
+func (s *keySet) methods() []string {
+	var methods []string
+	exists := make(map[string]bool)
+	for _, key := range s.list() {
+		if alg := key.method.Alg(); !exists[alg] {
+			exists[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
+
+// loadKey 根据签名算法加载签名密钥及验证密钥
+// HS系列使用共享密钥；非对称算法未配置私钥时signKey为nil，仅可验证token
+func loadKey(key Key) (loaded *signingKey, err error) {
+	if key.SigningMethod == "" {
+		key.SigningMethod = DefaultSigningMethod
+	}
+	method := jwt.GetSigningMethod(key.SigningMethod)
+	if method == nil || method == jwt.SigningMethodNone {
+		err = gerror.NewCodef(gcode.CodeInvalidConfiguration, "%s: %s", MsgErrSigningMethod, key.SigningMethod)
+		return
+	}
+	loaded = &signingKey{kid: key.Kid, method: method}
+	if _, ok := method.(*jwt.SigningMethodHMAC); ok {
+		loaded.signKey, loaded.verifyKey = key.EncryptKey, key.EncryptKey
+		return
+	}
+
+	privatePem := key.PrivateKey
+	if len(privatePem) == 0 && key.PrivateKeyFile != "" {
+		privatePem = gfile.GetBytes(key.PrivateKeyFile)
+	}
+	publicPem := key.PublicKey
+	if len(publicPem) == 0 && key.PublicKeyFile != "" {
+		publicPem = gfile.GetBytes(key.PublicKeyFile)
+	}
+
+	if len(privatePem) > 0 {
+		if loaded.signKey, err = parsePrivateKey(method, privatePem); err != nil {
+			return nil, err
+		}
+	}
+	if len(publicPem) > 0 {
+		if loaded.verifyKey, err = parsePublicKey(method, publicPem); err != nil {
+			return nil, err
+		}
+	} else if signer, ok := loaded.signKey.(crypto.Signer); ok {
+		loaded.verifyKey = signer.Public()
+	}
+	if loaded.verifyKey == nil {
+		return nil, gerror.NewCode(gcode.CodeInvalidConfiguration, MsgErrPublicKey)
+	}
+	return
+}
+
+// parsePrivateKey 解析PEM格式私钥
+func parsePrivateKey(method jwt.SigningMethod, pem []byte) (key any, err error) {
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		key, err = jwt.ParseRSAPrivateKeyFromPEM(pem)
+	case *jwt.SigningMethodECDSA:
+		key, err = jwt.ParseECPrivateKeyFromPEM(pem)
+	case *jwt.SigningMethodEd25519:
+		key, err = jwt.ParseEdPrivateKeyFromPEM(pem)
+	default:
+		err = gerror.NewCodef(gcode.CodeInvalidConfiguration, "%s: %s", MsgErrSigningMethod, method.Alg())
+	}
+	if err != nil && gerror.Code(err) == gcode.CodeNil {
+		err = gerror.WrapCode(gcode.CodeInvalidConfiguration, err, "parse private key fail")
+	}
+	return
+}
+
+// parsePublicKey 解析PEM格式公钥
+func parsePublicKey(method jwt.SigningMethod, pem []byte) (key any, err error) {
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		key, err = jwt.ParseRSAPublicKeyFromPEM(pem)
+	case *jwt.SigningMethodECDSA:
+		key, err = jwt.ParseECPublicKeyFromPEM(pem)
+	case *jwt.SigningMethodEd25519:
+		key, err = jwt.ParseEdPublicKeyFromPEM(pem)
+	default:
+		err = gerror.NewCodef(gcode.CodeInvalidConfiguration, "%s: %s", MsgErrSigningMethod, method.Alg())
+	}
+	if err != nil && gerror.Code(err) == gcode.CodeNil {
+		err = gerror.WrapCode(gcode.CodeInvalidConfiguration, err, "parse public key fail")
+	}
+	return
+}
@@ -0,0 +1,112 @@
+package gtoken_jwt_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/goflyfox/gtoken-jwt/v2"
+	"github.com/goflyfox/gtoken/v2/gtoken"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/os/gfile"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// pemKeys 生成PEM格式私钥及公钥
+func pemKeys(t *testing.T, key any, public any) (privatePem, publicPem []byte) {
+	privateBytes, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+	publicBytes, err := x509.MarshalPKIXPublicKey(public)
+	assert.NoError(t, err)
+	privatePem = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateBytes})
+	publicPem = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicBytes})
+	return
+}
+
+func TestSigningMethod(t *testing.T) {
+	ctx := gctx.New()
+	userKey := "testUser"
+	data := g.Map{"a": "1"}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	for method, keys := range map[string][2]any{
+		"RS256": {rsaKey, &rsaKey.PublicKey},
+		"PS256": {rsaKey, &rsaKey.PublicKey},
+		"ES256": {ecKey, &ecKey.PublicKey},
+		"EdDSA": {edKey, edPublic},
+	} {
+		privatePem, publicPem := pemKeys(t, keys[0], keys[1])
+		issuer := gtoken_jwt.NewWithOptions(gtoken_jwt.Options{
+			SigningMethod: method,
+			PrivateKey:    privatePem,
+		})
+		token, err := issuer.Generate(ctx, userKey, data)
+		assert.NoError(t, err)
+		header, _, err := jwt.NewParser().ParseUnverified(token, &jwt.RegisteredClaims{})
+		assert.NoError(t, err)
+		assert.Equal(t, method, header.Method.Alg())
+
+		// 仅配置公钥，只能验证token
+		verifier := gtoken_jwt.NewWithOptions(gtoken_jwt.Options{
+			SigningMethod: method,
+			PublicKey:     publicPem,
+		})
+		userKey2, data2, err := verifier.ParseToken(ctx, token)
+		assert.NoError(t, err, method)
+		assert.Equal(t, userKey, userKey2)
+		assert.Equal(t, data, data2)
+		_, err = verifier.Generate(ctx, userKey, data)
+		assert.Error(t, err)
+
+		// 使用公钥作为HS256密钥伪造token，算法不匹配拒绝
+		forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"UserKey": userKey}).SignedString(publicPem)
+		assert.NoError(t, err)
+		_, err = verifier.Validate(ctx, forged)
+		assert.Error(t, err)
+	}
+
+	// 从文件加载密钥
+	privatePem, publicPem := pemKeys(t, edKey, edPublic)
+	privateFile := gfile.Temp("gtoken-jwt-test", "ed25519.key")
+	publicFile := gfile.Temp("gtoken-jwt-test", "ed25519.pub")
+	defer gfile.Remove(gfile.Dir(privateFile))
+	assert.NoError(t, gfile.PutBytes(privateFile, privatePem))
+	assert.NoError(t, gfile.PutBytes(publicFile, publicPem))
+	issuer := gtoken_jwt.NewWithOptions(gtoken_jwt.Options{SigningMethod: "EdDSA", PrivateKeyFile: privateFile})
+	verifier := gtoken_jwt.NewWithOptions(gtoken_jwt.Options{SigningMethod: "EdDSA", PublicKeyFile: publicFile})
+	token, err := issuer.Generate(ctx, userKey, data)
+	assert.NoError(t, err)
+	userKey2, err := verifier.Validate(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, userKey, userKey2)
+
+	// 配置错误
+	assert.Panics(t, func() {
+		gtoken_jwt.NewWithOptions(gtoken_jwt.Options{SigningMethod: "none"})
+	})
+	assert.Panics(t, func() {
+		gtoken_jwt.NewWithOptions(gtoken_jwt.Options{SigningMethod: "RS256"})
+	})
+	assert.Panics(t, func() {
+		gtoken_jwt.NewWithOptions(gtoken_jwt.Options{SigningMethod: "RS256", PublicKey: []byte("bad")})
+	})
+	// 默认HS256兼容原有配置
+	hsToken := gtoken_jwt.New(gtoken.Options{EncryptKey: []byte("12345678912345678912345678912345")})
+	token, err = hsToken.Generate(ctx, userKey, data)
+	assert.NoError(t, err)
+	_, err = hsToken.Validate(ctx, token)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,73 @@
+package gtoken_jwt
+
+import (
+	"fmt"
+	"github.com/goflyfox/gtoken/v2/gtoken"
+)
+
+const (
+	DefaultSigningMethod = "HS256"
+	DefaultCachePreKey   = "GTokenJwt:"
+)
+
+// Options jwt配置项，兼容gtoken配置项
+type Options struct {
+	gtoken.Options
+	// SigningMethod 签名算法 默认HS256，支持HS256/384/512、RS256/384/512、PS256/384/512、ES256/384/512、EdDSA
+	// HS系列使用EncryptKey作为共享密钥，其他算法使用私钥签名、公钥验证
+	SigningMethod string
+	// PrivateKey PEM格式私钥，用于生成token；仅验证token的服务可不配置
+	PrivateKey []byte
+	// PublicKey PEM格式公钥，用于验证token；为空时从私钥获取
+	PublicKey []byte
+	// PrivateKeyFile PEM格式私钥文件路径，PrivateKey为空时读取
+	PrivateKeyFile string
+	// PublicKeyFile PEM格式公钥文件路径，PublicKey为空时读取
+	PublicKeyFile string
+	// Keys 密钥列表，用于密钥轮换；配置后忽略上面的单密钥配置
+	Keys []Key
+	// ActiveKid 签名密钥ID，为空时使用Keys中第一个配置私钥的密钥
+	ActiveKid string
+	// Denylist 是否启用注销名单
+	Denylist bool
+	// Track 是否启用跟踪模式，记录签发的token，支持Get及MaxRefresh、MaxRefreshTimes续签
+	Track bool
+	// Cache 注销名单及跟踪缓存，为空时按CacheMode、CachePreKey创建缓存
+	Cache gtoken.Cache `json:"-"`
+	// Issuer 签发者iss，配置后验证token签发者必须一致
+	Issuer string
+	// Audience 接收者aud，生成时写入；配置后验证token接收者需包含其中之一
+	Audience []string
+	// Subject 是否写入sub（userKey）
+	Subject bool
+	// NotBefore 是否写入nbf（生成时间）
+	NotBefore bool
+	// IssuedAt 是否写入iat（生成时间），写入后验证iat不能晚于当前时间
+	IssuedAt bool
+	// Leeway 验证exp、nbf、iat时允许的时钟偏差（毫秒）
+	Leeway int64
+	// JweAlgorithm JWE加密算法，配置后token签名后再加密：dir、RSA-OAEP、RSA-OAEP-256，内容加密使用A256GCM
+	JweAlgorithm string
+	// JweKid JWE头部kid
+	JweKid string
+	// JweKey dir模式32字节内容密钥
+	JweKey []byte
+	// JwePublicKey RSA-OAEP模式PEM格式加密公钥，为空时从私钥获取
+	JwePublicKey []byte
+	// JwePrivateKey RSA-OAEP模式PEM格式解密私钥，仅生成token的服务可不配置
+	JwePrivateKey []byte
+	// JwePublicKeyFile RSA-OAEP模式PEM格式加密公钥文件路径
+	JwePublicKeyFile string
+	// JwePrivateKeyFile RSA-OAEP模式PEM格式解密私钥文件路径
+	JwePrivateKeyFile string
+}
+
+func (o *Options) String() string {
+	return fmt.Sprintf("Options{%s"+
+		", SigningMethod:%s, PrivateKeyFile:%s, PublicKeyFile:%s, Keys:%d, ActiveKid:%s, Denylist:%v, Track:%v"+
+		", Issuer:%s, Audience:%v, Subject:%v, NotBefore:%v, IssuedAt:%v, Leeway:%d"+
+		", JweAlgorithm:%s, JweKid:%s"+
+		"}", o.Options.String(), o.SigningMethod, o.PrivateKeyFile, o.PublicKeyFile, len(o.Keys), o.ActiveKid,
+		o.Denylist, o.Track, o.Issuer, o.Audience, o.Subject, o.NotBefore, o.IssuedAt, o.Leeway,
+		o.JweAlgorithm, o.JweKid)
+}
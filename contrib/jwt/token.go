@@ -3,11 +3,15 @@ package gtoken_jwt
 import (
 	"context"
 	"github.com/goflyfox/gtoken/v2/gtoken"
+	"github.com/gogf/gf/v2/container/gvar"
 	"github.com/gogf/gf/v2/errors/gcode"
 	"github.com/gogf/gf/v2/errors/gerror"
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/text/gstr"
+	"github.com/gogf/gf/v2/util/guid"
 	"github.com/golang-jwt/jwt/v5"
+	"slices"
 	"time"
 )
 
@@ -17,12 +21,19 @@ const (
 
 // JwtToken jwt结构体
 type JwtToken struct {
-	Options gtoken.Options
+	Options    gtoken.Options
+	JwtOptions Options
+	keys       *keySet
+	cache      gtoken.Cache  // 注销名单及跟踪缓存
+	denylist   bool          // 是否启用注销名单
+	track      bool          // 是否启用跟踪模式
+	jwe        *jweEncrypter // JWE加解密，为nil时不加密
 }
 
 type JwtClaims struct {
 	*JwtData
 	jwt.RegisteredClaims
+	IssuedNano int64 `json:"iat_ns,omitempty"` // 自定义声明token签发时间（纳秒），用于用户注销判断
 }
 
 type JwtData struct {
@@ -32,7 +43,7 @@ type JwtData struct {
 }
 
 func NewByConfig() gtoken.Token {
-	var options *gtoken.Options
+	var options *Options
 	ctx := gctx.New()
 	err := g.Cfg().MustGet(ctx, "gToken").Struct(&options)
 	if err != nil {
@@ -41,47 +52,149 @@ func NewByConfig() gtoken.Token {
 	if options == nil {
 		panic("options config not configured")
 	}
-	return New(*options)
+	return NewWithOptions(*options)
 }
 
 // New
-// 说明：此token不支持刷新，不支持多端登录，仅适用于短期或者一次性token的使用场景
+// 说明：此token不支持刷新，不支持多端登录，仅适用于短期或者一次性token的使用场景；需要刷新时使用NewWithOptions启用跟踪模式
 func New(options gtoken.Options) gtoken.Token {
+	return NewWithOptions(Options{Options: options})
+}
+
+// NewWithOptions 通过jwt配置项创建token，支持非对称签名算法
+// 密钥配置错误时panic
+func NewWithOptions(options Options) *JwtToken {
 	if options.Timeout == 0 {
 		options.Timeout = DefaultShortTimeout
 	}
 	if len(options.EncryptKey) == 0 {
 		options.EncryptKey = []byte(gtoken.DefaultEncryptKey)
 	}
+	if options.SigningMethod == "" {
+		options.SigningMethod = DefaultSigningMethod
+	}
+	keys, err := newKeySet(options)
+	if err != nil {
+		panic(err)
+	}
+	jwe, err := newJweEncrypter(options)
+	if err != nil {
+		panic(err)
+	}
 
 	gfToken := &JwtToken{
-		Options: options,
+		Options:    options.Options,
+		JwtOptions: options,
+		keys:       keys,
+		cache:      newCache(options),
+		denylist:   options.Denylist,
+		track:      options.Track,
+		jwe:        jwe,
 	}
 	g.Log().Debug(gctx.New(), "token options", options.String())
 	return gfToken
 }
 
+// newCache 根据配置创建注销名单及跟踪缓存，缓存超时时间为token超时时间加时钟偏差
+func newCache(options Options) gtoken.Cache {
+	if !options.Denylist && !options.Track {
+		return nil
+	}
+	if options.Cache != nil {
+		return options.Cache
+	}
+	preKey := options.CachePreKey
+	if preKey == "" {
+		preKey = DefaultCachePreKey
+	}
+	// 验证时允许时钟偏差，记录需保留到偏差结束
+	cache := gtoken.NewDefaultCache(options.CacheMode, preKey, options.Timeout+options.Leeway)
+	if len(options.CacheEncryptKey) > 0 {
+		// 缓存数据加密key不能与HS签名密钥相同
+		encryptKeys := [][]byte{options.EncryptKey}
+		for _, key := range options.Keys {
+			encryptKeys = append(encryptKeys, key.EncryptKey)
+		}
+		if err := gtoken.CheckCacheEncryptKey(options.CacheEncryptKey, encryptKeys...); err != nil {
+			panic(err)
+		}
+		cache.DataKey = options.CacheEncryptKey
+	}
+	return cache
+}
+
 // Generate 生成 Token
+// 跟踪模式下记录用户最新token
 func (m *JwtToken) Generate(ctx context.Context, userKey string, data any) (token string, err error) {
 	if userKey == "" {
 		err = gerror.NewCode(gcode.CodeMissingParameter, gtoken.MsgErrUserKeyEmpty)
 		return
 	}
-	claims := JwtClaims{
-		&JwtData{
+	token, jwtClaims, err := m.sign(userKey, data)
+	if err != nil {
+		return
+	}
+	if m.track {
+		if err = m.saveTrack(ctx, token, jwtClaims); err != nil {
+			err = gerror.WrapCode(gcode.CodeInternalError, err)
+			return "", err
+		}
+	}
+
+	return
+}
+
+// sign 签发token
+func (m *JwtToken) sign(userKey string, data any) (token string, jwtClaims *JwtClaims, err error) {
+	now := time.Now()
+	jwtClaims = &JwtClaims{
+		JwtData: &JwtData{
 			UserKey: userKey,
 			Data:    data,
-			Now:     time.Now().UnixNano(),
-		},
-		jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(m.Options.Timeout) * time.Millisecond)),
+			Now:     now.UnixNano(),
 		},
+		RegisteredClaims: m.registeredClaims(userKey, now),
 	}
-	token, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.Options.EncryptKey)
-	if err != nil {
-		return
+	token, err = m.signClaims(jwtClaims)
+	return
+}
+
+// registeredClaims 生成标准声明
+func (m *JwtToken) registeredClaims(userKey string, now time.Time) jwt.RegisteredClaims {
+	registered := jwt.RegisteredClaims{
+		ID:        guid.S(),
+		Issuer:    m.JwtOptions.Issuer,
+		Audience:  m.JwtOptions.Audience,
+		ExpiresAt: jwt.NewNumericDate(now.Add(time.Duration(m.Options.Timeout) * time.Millisecond)),
+	}
+	if m.JwtOptions.Subject {
+		registered.Subject = userKey
+	}
+	if m.JwtOptions.NotBefore {
+		registered.NotBefore = jwt.NewNumericDate(now)
+	}
+	if m.JwtOptions.IssuedAt {
+		registered.IssuedAt = jwt.NewNumericDate(now)
 	}
+	return registered
+}
 
+// signClaims 使用当前签名密钥签名，启用JWE时加密
+func (m *JwtToken) signClaims(claims jwt.Claims) (token string, err error) {
+	key := m.keys.signer()
+	if key == nil || key.signKey == nil {
+		err = gerror.NewCode(gcode.CodeInvalidConfiguration, MsgErrPrivateKey)
+		return
+	}
+	jwtToken := jwt.NewWithClaims(key.method, claims)
+	if key.kid != "" {
+		jwtToken.Header["kid"] = key.kid
+	}
+	token, err = jwtToken.SignedString(key.signKey)
+	if err != nil || m.jwe == nil {
+		return
+	}
+	token, err = m.jwe.encrypt(token)
 	return
 }
 
@@ -92,31 +205,30 @@ func (m *JwtToken) Validate(ctx context.Context, token string) (userKey string,
 		return
 	}
 
-	jwtToken, err := jwt.ParseWithClaims(token, &JwtClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return m.Options.EncryptKey, nil
-	})
-
+	jwtClaims, payload, err := m.parsePayload(ctx, token)
 	if err != nil {
-		return "", err
-	}
-
-	if !jwtToken.Valid {
-		err = gerror.NewCode(gcode.CodeMissingParameter, gtoken.MsgErrValidate)
 		return
 	}
-
-	jwtClaims, ok := jwtToken.Claims.(*JwtClaims)
-	if !ok {
-		err = gerror.NewCode(gcode.CodeMissingParameter, gtoken.MsgErrValidate)
-		return
+	if m.track {
+		if err = m.renew(ctx, jwtClaims, payload); err != nil {
+			return
+		}
 	}
 
 	return jwtClaims.UserKey, nil
 }
 
-// Get 通过userKey获取token
+// Get 通过userKey获取token，需启用跟踪模式
 func (m *JwtToken) Get(ctx context.Context, userKey string) (token string, data any, err error) {
-	panic("get method does not support!")
+	if userKey == "" {
+		err = gerror.NewCode(gcode.CodeMissingParameter, gtoken.MsgErrUserKeyEmpty)
+		return
+	}
+	if !m.track {
+		err = gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+		return
+	}
+	return m.getTrack(ctx, userKey)
 }
 
 // ParseToken 通过token获取userKey
@@ -126,12 +238,32 @@ func (m *JwtToken) ParseToken(ctx context.Context, token string) (userKey string
 		return
 	}
 
-	jwtToken, err := jwt.ParseWithClaims(token, &JwtClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return m.Options.EncryptKey, nil
-	})
+	jwtClaims, err := m.parse(ctx, token)
+	if err != nil {
+		return
+	}
+
+	return jwtClaims.UserKey, jwtClaims.Data, nil
+}
+
+// parse 解析并验证token，启用JWE时先解密，通过kid选择验证密钥，仅接受密钥配置的签名算法
+// 启用注销名单时，校验token是否已注销；启用跟踪模式时，校验token是否被销毁或剔除
+func (m *JwtToken) parse(ctx context.Context, token string) (jwtClaims *JwtClaims, err error) {
+	jwtClaims, _, err = m.parsePayload(ctx, token)
+	return
+}
+
+// parsePayload 解析并验证token，同时返回jwt payload原文
+func (m *JwtToken) parsePayload(ctx context.Context, token string) (jwtClaims *JwtClaims, payload []byte, err error) {
+	if m.jwe != nil {
+		if token, err = m.jwe.decrypt(token); err != nil {
+			return
+		}
+	}
+	jwtToken, err := jwt.ParseWithClaims(token, &JwtClaims{}, m.keyFunc, m.parserOptions()...)
 
 	if err != nil {
-		return "", nil, err
+		return nil, nil, err
 	}
 
 	if !jwtToken.Valid {
@@ -144,15 +276,204 @@ func (m *JwtToken) ParseToken(ctx context.Context, token string) (userKey string
 		err = gerror.NewCode(gcode.CodeMissingParameter, gtoken.MsgErrValidate)
 		return
 	}
+	// 自定义声明token的userKey为标准声明sub
+	if jwtClaims.JwtData == nil {
+		jwtClaims.JwtData = &JwtData{UserKey: jwtClaims.Subject}
+	}
+	if jwtClaims.UserKey == "" {
+		err = gerror.NewCode(gcode.CodeMissingParameter, gtoken.MsgErrValidate)
+		return
+	}
+	if err = m.validateClaims(jwtClaims); err != nil {
+		return nil, nil, err
+	}
+	if m.denylist {
+		revoked, e := m.isRevoked(ctx, jwtClaims)
+		if e != nil {
+			return nil, nil, gerror.WrapCode(gcode.CodeInternalError, e)
+		}
+		if revoked {
+			return nil, nil, gerror.NewCode(gtoken.CodeTokenRevoked, gtoken.MsgErrTokenRevoked)
+		}
+	}
+	if m.track {
+		if err = m.checkTrack(ctx, jwtClaims); err != nil {
+			return nil, nil, err
+		}
+	}
+	if payload, err = decodeSegment(gstr.Split(jwtToken.Raw, ".")[1]); err != nil {
+		return nil, nil, gerror.WrapCode(gcode.CodeInvalidParameter, err)
+	}
+	return jwtClaims, payload, nil
+}
 
-	return jwtClaims.UserKey, jwtClaims.Data, nil
+// parserOptions 解析选项，验证签名算法、签发者及时钟偏差
+func (m *JwtToken) parserOptions() []jwt.ParserOption {
+	options := []jwt.ParserOption{jwt.WithValidMethods(m.keys.methods())}
+	if m.JwtOptions.Issuer != "" {
+		options = append(options, jwt.WithIssuer(m.JwtOptions.Issuer))
+	}
+	if m.JwtOptions.IssuedAt {
+		options = append(options, jwt.WithIssuedAt())
+	}
+	if m.JwtOptions.Leeway > 0 {
+		options = append(options, jwt.WithLeeway(time.Duration(m.JwtOptions.Leeway)*time.Millisecond))
+	}
+	return options
+}
+
+// validateClaims 验证接收者aud包含配置的接收者之一，sub与userKey一致
+func (m *JwtToken) validateClaims(jwtClaims *JwtClaims) error {
+	if len(m.JwtOptions.Audience) > 0 {
+		var matched bool
+		for _, audience := range m.JwtOptions.Audience {
+			if slices.Contains(jwtClaims.Audience, audience) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return gerror.WrapCode(gcode.CodeInvalidParameter, jwt.ErrTokenInvalidAudience)
+		}
+	}
+	if jwtClaims.Subject != "" && jwtClaims.Subject != jwtClaims.UserKey {
+		return gerror.WrapCode(gcode.CodeInvalidParameter, jwt.ErrTokenInvalidSubject)
+	}
+	return nil
+}
+
+// keyFunc 通过token头部kid获取验证密钥
+func (m *JwtToken) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := m.keys.get(kid)
+	if !ok {
+		return nil, gerror.NewCodef(gcode.CodeInvalidParameter, "%s: %s", MsgErrKeyNotFound, kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, gerror.NewCode(gcode.CodeInvalidParameter, MsgErrSigningMethod)
+	}
+	return key.verifyKey, nil
+}
+
+// AddKey 加入密钥，kid相同时覆盖；新密钥可用于验证，调用SetActiveKey后用于签名
+func (m *JwtToken) AddKey(key Key) error {
+	if key.Kid == "" {
+		return gerror.NewCode(gcode.CodeMissingParameter, MsgErrKidEmpty)
+	}
+	return m.keys.add(m.JwtOptions, key)
+}
+
+// RemoveKey 移除密钥，移除后使用该密钥签名的token验证失败
+func (m *JwtToken) RemoveKey(kid string) error {
+	return m.keys.remove(kid)
+}
+
+// SetActiveKey 设置签名密钥
+func (m *JwtToken) SetActiveKey(kid string) error {
+	return m.keys.setActive(kid)
 }
 
 // Destroy 通过userKey销毁Token
+// 启用注销名单或跟踪模式时，此前签发的token全部失效；否则jwt为无状态token，不做处理
 func (m *JwtToken) Destroy(ctx context.Context, userKey string) error {
+	if userKey == "" {
+		return gerror.NewCode(gcode.CodeMissingParameter, gtoken.MsgErrUserKeyEmpty)
+	}
+	if m.track {
+		if err := m.cache.Remove(ctx, CacheKeyTrack+userKey); err != nil {
+			return gerror.WrapCode(gcode.CodeInternalError, err)
+		}
+	}
+	if m.denylist {
+		return m.revokeUser(ctx, userKey)
+	}
 	return nil
 }
 
+// Sessions jwt为无状态token，不支持会话查询
+func (m *JwtToken) Sessions(ctx context.Context, userKey string) (sessions []gtoken.Session, err error) {
+	return nil, gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+}
+
+// DestroySession jwt为无状态token，不支持销毁单个会话
+func (m *JwtToken) DestroySession(ctx context.Context, userKey, sessionId string) error {
+	return gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+}
+
+// DestroyToken 销毁单个token，需启用注销名单或跟踪模式
+func (m *JwtToken) DestroyToken(ctx context.Context, token string) error {
+	if token == "" {
+		return gerror.NewCode(gcode.CodeMissingParameter, gtoken.MsgErrTokenEmpty)
+	}
+	if !m.denylist && !m.track {
+		return gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+	}
+	jwtClaims, err := m.parse(ctx, token)
+	if err != nil {
+		return err
+	}
+	if m.track {
+		if err = m.removeTrack(ctx, jwtClaims); err != nil {
+			return gerror.WrapCode(gcode.CodeInternalError, err)
+		}
+	}
+	if m.denylist {
+		return m.revokeToken(ctx, jwtClaims)
+	}
+	return nil
+}
+
+// UpdateData jwt为无状态token，不支持更新数据
+func (m *JwtToken) UpdateData(ctx context.Context, userKey string, data any) error {
+	return gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+}
+
+// UpdateSessionData jwt为无状态token，不支持更新数据
+func (m *JwtToken) UpdateSessionData(ctx context.Context, userKey, sessionId string, data any) error {
+	return gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+}
+
+// Values jwt为无状态token，不支持会话键值
+func (m *JwtToken) Values(ctx context.Context, token string) (values g.Map, version int64, err error) {
+	err = gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+	return
+}
+
+// GetValue jwt为无状态token，不支持会话键值
+func (m *JwtToken) GetValue(ctx context.Context, token, key string) (value *gvar.Var, err error) {
+	return nil, gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+}
+
+// SetValue jwt为无状态token，不支持会话键值
+func (m *JwtToken) SetValue(ctx context.Context, token, key string, value any) (version int64, err error) {
+	err = gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+	return
+}
+
+// SetValueIfVersion jwt为无状态token，不支持会话键值
+func (m *JwtToken) SetValueIfVersion(ctx context.Context, token, key string, value any, version int64) (newVersion int64, err error) {
+	err = gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+	return
+}
+
+// DeleteValue jwt为无状态token，不支持会话键值
+func (m *JwtToken) DeleteValue(ctx context.Context, token, key string) (version int64, err error) {
+	err = gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+	return
+}
+
+// GeneratePair jwt token暂不支持双token
+func (m *JwtToken) GeneratePair(ctx context.Context, userKey string, data any) (pair gtoken.TokenPair, err error) {
+	err = gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+	return
+}
+
+// Refresh jwt token暂不支持双token
+func (m *JwtToken) Refresh(ctx context.Context, refreshToken string) (pair gtoken.TokenPair, err error) {
+	err = gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+	return
+}
+
 // GetOptions 获取Options配置
 func (m *JwtToken) GetOptions() gtoken.Options {
 	return m.Options
@@ -9,6 +9,7 @@ import (
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/gogf/gf/v2/os/gctx"
 	"github.com/gogf/gf/v2/os/glog"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -98,3 +99,88 @@ func TestTimeOut(t *testing.T) {
 
 	}
 }
+
+func TestParseTokenAs(t *testing.T) {
+	type UserData struct {
+		Name  string   `json:"name"`
+		Roles []string `json:"roles"`
+	}
+	var (
+		ctx     = gctx.New()
+		userKey = "testUser5"
+		data    = UserData{Name: "flyFox", Roles: []string{"admin"}}
+	)
+	gToken := gtoken_jwt.New(gtoken.Options{})
+	token, err := gToken.Generate(ctx, userKey, data)
+	assert.NoError(t, err)
+	userKey2, data2, err := gtoken.ParseTokenAs[UserData](ctx, gToken, token)
+	assert.NoError(t, err)
+	assert.Equal(t, userKey, userKey2)
+	assert.Equal(t, data, data2)
+}
+
+func TestRegisteredClaims(t *testing.T) {
+	ctx := gctx.New()
+	userKey := "testUser"
+	encryptKey := []byte("12345678912345678912345678912345")
+
+	adminToken := gtoken_jwt.NewWithOptions(gtoken_jwt.Options{
+		Options:   gtoken.Options{EncryptKey: encryptKey},
+		Issuer:    "gtoken",
+		Audience:  []string{"admin-api"},
+		Subject:   true,
+		NotBefore: true,
+		IssuedAt:  true,
+	})
+	customerToken := gtoken_jwt.NewWithOptions(gtoken_jwt.Options{
+		Options:  gtoken.Options{EncryptKey: encryptKey},
+		Issuer:   "gtoken",
+		Audience: []string{"customer-api"},
+	})
+	otherIssuer := gtoken_jwt.NewWithOptions(gtoken_jwt.Options{
+		Options:  gtoken.Options{EncryptKey: encryptKey},
+		Issuer:   "other",
+		Audience: []string{"admin-api"},
+	})
+
+	token, err := adminToken.Generate(ctx, userKey, nil)
+	assert.NoError(t, err)
+	claims := jwt.MapClaims{}
+	_, _, err = jwt.NewParser().ParseUnverified(token, claims)
+	assert.NoError(t, err)
+	assert.Equal(t, "gtoken", claims["iss"])
+	assert.Equal(t, userKey, claims["sub"])
+	assert.Contains(t, claims, "nbf")
+	assert.Contains(t, claims, "iat")
+
+	u, err := adminToken.Validate(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, userKey, u)
+	// 共享密钥，接收者不一致拒绝
+	_, err = customerToken.Validate(ctx, token)
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidAudience)
+	// 签发者不一致拒绝
+	_, err = otherIssuer.Validate(ctx, token)
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidIssuer)
+
+	// 时钟偏差
+	future, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"UserKey": userKey,
+		"iss":     "gtoken",
+		"aud":     "admin-api",
+		"nbf":     time.Now().Add(2 * time.Second).Unix(),
+		"exp":     time.Now().Add(time.Minute).Unix(),
+	}).SignedString(encryptKey)
+	assert.NoError(t, err)
+	_, err = adminToken.Validate(ctx, future)
+	assert.ErrorIs(t, err, jwt.ErrTokenNotValidYet)
+	leewayToken := gtoken_jwt.NewWithOptions(gtoken_jwt.Options{
+		Options:  gtoken.Options{EncryptKey: encryptKey},
+		Issuer:   "gtoken",
+		Audience: []string{"customer-api", "admin-api"},
+		Leeway:   5000,
+	})
+	u, err = leewayToken.Validate(ctx, future)
+	assert.NoError(t, err)
+	assert.Equal(t, userKey, u)
+}
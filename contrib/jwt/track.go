@@ -0,0 +1,160 @@
+package gtoken_jwt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/goflyfox/gtoken/v2/gtoken"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/util/gconv"
+	"github.com/golang-jwt/jwt/v5"
+	"time"
+)
+
+const (
+	CacheKeyTrack = "track:" // 跟踪记录，key为userKey
+
+	KeyJti     = "jti"     // 当前token jti
+	KeyPrevJti = "prevJti" // 续签前token jti，过期前仍然有效
+)
+
+// saveTrack 记录用户最新token
+func (m *JwtToken) saveTrack(ctx context.Context, token string, jwtClaims *JwtClaims) error {
+	return m.cache.Set(ctx, CacheKeyTrack+jwtClaims.UserKey, g.Map{
+		gtoken.KeyUserKey:    jwtClaims.UserKey,
+		gtoken.KeyToken:      token,
+		gtoken.KeyData:       jwtClaims.Data,
+		KeyJti:               jwtClaims.ID,
+		gtoken.KeyCreateTime: time.Now().UnixMilli(),
+		gtoken.KeyRefreshNum: 0,
+	})
+}
+
+// getTrack 获取用户最新token及数据
+func (m *JwtToken) getTrack(ctx context.Context, userKey string) (token string, data any, err error) {
+	trackCache, err := m.cache.Get(ctx, CacheKeyTrack+userKey)
+	if err != nil {
+		err = gerror.WrapCode(gcode.CodeInternalError, err)
+		return
+	}
+	if trackCache == nil {
+		err = gerror.NewCode(gcode.CodeInternalError, gtoken.MsgErrDataEmpty)
+		return
+	}
+	if time.Now().UnixMilli() > gconv.Int64(trackCache[gtoken.KeyCreateTime])+m.Options.Timeout {
+		err = gerror.NewCode(gtoken.CodeTokenExpired, gtoken.MsgErrTokenExpired)
+		return
+	}
+	return gconv.String(trackCache[gtoken.KeyToken]), trackCache[gtoken.KeyData], nil
+}
+
+// checkTrack 校验token跟踪记录
+// 记录不存在说明已销毁；非多端登录时，仅最新token有效
+func (m *JwtToken) checkTrack(ctx context.Context, jwtClaims *JwtClaims) error {
+	trackCache, err := m.cache.Get(ctx, CacheKeyTrack+jwtClaims.UserKey)
+	if err != nil {
+		return gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	if trackCache == nil {
+		return gerror.NewCode(gcode.CodeInternalError, gtoken.MsgErrDataEmpty)
+	}
+	if !m.Options.MultiLogin && jwtClaims.ID != gconv.String(trackCache[KeyJti]) &&
+		jwtClaims.ID != gconv.String(trackCache[KeyPrevJti]) {
+		return gerror.NewCode(gtoken.CodeKickedOut, gtoken.MsgErrKickedOut)
+	}
+	return nil
+}
+
+// renew 达到MaxRefresh时续签token，新token写入跟踪记录及响应头
+// 续签token沿用原token声明，旧token在过期前仍然有效，避免并发请求失败
+func (m *JwtToken) renew(ctx context.Context, jwtClaims *JwtClaims, payload []byte) error {
+	if m.Options.MaxRefresh == 0 {
+		return nil
+	}
+	cacheKey := CacheKeyTrack + jwtClaims.UserKey
+	trackCache, err := m.cache.Get(ctx, cacheKey)
+	if err != nil {
+		return gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	if !m.needRenew(trackCache, jwtClaims, time.Now().UnixMilli()) {
+		return nil
+	}
+
+	var token string
+	err = gtoken.UpdateCacheValue(ctx, m.cache, cacheKey, func(trackCache g.Map) (g.Map, error) {
+		nowTime := time.Now().UnixMilli()
+		if !m.needRenew(trackCache, jwtClaims, nowTime) {
+			return trackCache, nil
+		}
+		newToken, jti, err := m.resign(payload)
+		if err != nil {
+			return nil, err
+		}
+		token = newToken
+		trackCache[gtoken.KeyToken] = newToken
+		trackCache[KeyPrevJti] = trackCache[KeyJti]
+		trackCache[KeyJti] = jti
+		trackCache[gtoken.KeyCreateTime] = nowTime
+		trackCache[gtoken.KeyRefreshNum] = gconv.Int(trackCache[gtoken.KeyRefreshNum]) + 1
+		return trackCache, nil
+	})
+	if err != nil {
+		return gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	if token != "" {
+		if r := g.RequestFromCtx(ctx); r != nil {
+			r.Response.Header().Set(gtoken.HeaderRenewToken, token)
+		}
+	}
+	return nil
+}
+
+// resign 使用原token声明重新签发，更新jti、过期时间及签发时间
+func (m *JwtToken) resign(payload []byte) (token, jti string, err error) {
+	claims := jwt.MapClaims{}
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err = decoder.Decode(&claims); err != nil {
+		return
+	}
+	now := time.Now()
+	registered := m.registeredClaims(gconv.String(claims[KeyClaimUserKey]), now)
+	jti = registered.ID
+	claims["jti"] = jti
+	claims["exp"] = registered.ExpiresAt.Unix()
+	for _, name := range []string{"nbf", "iat"} {
+		if _, ok := claims[name]; ok {
+			claims[name] = now.Unix()
+		}
+	}
+	for _, name := range []string{KeyClaimNow, KeyClaimIatNano} {
+		if _, ok := claims[name]; ok {
+			claims[name] = now.UnixNano()
+		}
+	}
+	token, err = m.signClaims(claims)
+	return
+}
+
+// needRenew 判断是否需要续签，仅续签最新token
+func (m *JwtToken) needRenew(trackCache g.Map, jwtClaims *JwtClaims, nowTime int64) bool {
+	if trackCache == nil || jwtClaims.ID != gconv.String(trackCache[KeyJti]) {
+		return false
+	}
+	if m.Options.MaxRefreshTimes > 0 && gconv.Int(trackCache[gtoken.KeyRefreshNum]) >= m.Options.MaxRefreshTimes {
+		return false
+	}
+	return nowTime > gconv.Int64(trackCache[gtoken.KeyCreateTime])+m.Options.MaxRefresh
+}
+
+// removeTrack 销毁最新token时删除跟踪记录
+func (m *JwtToken) removeTrack(ctx context.Context, jwtClaims *JwtClaims) error {
+	return gtoken.UpdateCacheValue(ctx, m.cache, CacheKeyTrack+jwtClaims.UserKey, func(trackCache g.Map) (g.Map, error) {
+		if trackCache == nil || jwtClaims.ID != gconv.String(trackCache[KeyJti]) {
+			return trackCache, nil
+		}
+		return nil, nil
+	})
+}
@@ -0,0 +1,134 @@
+package gtoken_jwt_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/goflyfox/gtoken-jwt/v2"
+	"github.com/goflyfox/gtoken/v2/gtoken"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/util/guid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrack(t *testing.T) {
+	ctx := gctx.New()
+	userKey := "testUser"
+	data := g.Map{"a": "1"}
+
+	// 未启用跟踪模式，Get返回不支持错误
+	{
+		gfToken := gtoken_jwt.New(gtoken.Options{})
+		_, _, err := gfToken.Get(ctx, userKey)
+		assert.Equal(t, gcode.CodeNotSupported, gerror.Code(err))
+	}
+
+	gfToken := gtoken_jwt.NewWithOptions(gtoken_jwt.Options{
+		Options: gtoken.Options{Timeout: 2000, CachePreKey: "GTokenJwtTrack:"},
+		Track:   true,
+	})
+	token1, err := gfToken.Generate(ctx, userKey, data)
+	assert.NoError(t, err)
+	token, data2, err := gfToken.Get(ctx, userKey)
+	assert.NoError(t, err)
+	assert.Equal(t, token1, token)
+	assert.Equal(t, data, data2)
+
+	// 非多端登录，旧token被剔除
+	token2, err := gfToken.Generate(ctx, userKey, data)
+	assert.NoError(t, err)
+	token, _, err = gfToken.Get(ctx, userKey)
+	assert.NoError(t, err)
+	assert.Equal(t, token2, token)
+	_, err = gfToken.Validate(ctx, token1)
+	assert.Equal(t, gtoken.CodeKickedOut, gerror.Code(err))
+	_, err = gfToken.Validate(ctx, token2)
+	assert.NoError(t, err)
+
+	// 销毁单个token及用户
+	assert.NoError(t, gfToken.DestroyToken(ctx, token2))
+	_, err = gfToken.Validate(ctx, token2)
+	assert.Error(t, err)
+	token3, err := gfToken.Generate(ctx, userKey, data)
+	assert.NoError(t, err)
+	assert.NoError(t, gfToken.Destroy(ctx, userKey))
+	_, err = gfToken.Validate(ctx, token3)
+	assert.Error(t, err)
+	_, _, err = gfToken.Get(ctx, userKey)
+	assert.Error(t, err)
+
+	// 多端登录，旧token仍然有效
+	multiToken := gtoken_jwt.NewWithOptions(gtoken_jwt.Options{
+		Options: gtoken.Options{Timeout: 2000, CachePreKey: "GTokenJwtTrackMulti:", MultiLogin: true},
+		Track:   true,
+	})
+	token1, err = multiToken.Generate(ctx, userKey, data)
+	assert.NoError(t, err)
+	_, err = multiToken.Generate(ctx, userKey, data)
+	assert.NoError(t, err)
+	_, err = multiToken.Validate(ctx, token1)
+	assert.NoError(t, err)
+}
+
+func TestTrackRenew(t *testing.T) {
+	ctx := gctx.New()
+	userKey := "testUser"
+
+	gfToken := gtoken_jwt.NewWithOptions(gtoken_jwt.Options{
+		Options: gtoken.Options{
+			Timeout:         3000,
+			MaxRefresh:      500,
+			MaxRefreshTimes: 1,
+			CachePreKey:     "GTokenJwtRenew:",
+		},
+		Track: true,
+	})
+	token1, err := gfToken.Generate(ctx, userKey, g.Map{"a": "1"})
+	assert.NoError(t, err)
+
+	s := g.Server(guid.S())
+	s.Group("/", func(group *ghttp.RouterGroup) {
+		group.Middleware(gtoken.NewDefaultMiddleware(gfToken).Auth)
+		group.ALL("/user", func(r *ghttp.Request) {
+			r.Response.Write(r.GetCtxVar(gtoken.KeyUserKey).String())
+		})
+	})
+	s.SetPort(0)
+	s.SetDumpRouterMap(false)
+	assert.NoError(t, s.Start())
+	defer s.Shutdown()
+	url := fmt.Sprintf("http://127.0.0.1:%d/user", s.GetListenedPort())
+	request := func(token string) (renewToken string) {
+		resp, err := g.Client().SetHeader("Authorization", "Bearer "+token).Get(ctx, url)
+		assert.NoError(t, err)
+		defer resp.Close()
+		assert.Equal(t, userKey, resp.ReadAllString())
+		return resp.Header.Get(gtoken.HeaderRenewToken)
+	}
+
+	// 未达到MaxRefresh，不续签
+	assert.Empty(t, request(token1))
+
+	// 达到MaxRefresh，续签新token，旧token过期前仍然有效
+	time.Sleep(600 * time.Millisecond)
+	token2 := request(token1)
+	assert.NotEmpty(t, token2)
+	assert.NotEqual(t, token1, token2)
+	token, data, err := gfToken.Get(ctx, userKey)
+	assert.NoError(t, err)
+	assert.Equal(t, token2, token)
+	assert.Equal(t, g.Map{"a": "1"}, data)
+	assert.Empty(t, request(token1))
+
+	// 超过MaxRefreshTimes，不再续签
+	time.Sleep(600 * time.Millisecond)
+	assert.Empty(t, request(token2))
+	token, _, err = gfToken.Get(ctx, userKey)
+	assert.NoError(t, err)
+	assert.Equal(t, token2, token)
+}
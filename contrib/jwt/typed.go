@@ -0,0 +1,129 @@
+package gtoken_jwt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/goflyfox/gtoken/v2/gtoken"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/golang-jwt/jwt/v5"
+	"time"
+)
+
+const (
+	KeyClaimUserKey = "UserKey" // JwtData userKey声明
+	KeyClaimNow     = "Now"     // JwtData生成时间声明
+	KeyClaimIatNano = "iat_ns"  // 自定义声明token签发时间（纳秒）声明
+
+	MsgErrClaims = "claims must be a struct or map"
+)
+
+// TypedToken 自定义声明token，T为声明结构体，字段按json名称平铺到jwt payload
+// 例如：type UserClaims struct { Roles []string `json:"roles"`; TenantId string `json:"tenant_id"` }
+type TypedToken[T any] struct {
+	*JwtToken
+}
+
+// NewTyped 创建自定义声明token，配置同NewWithOptions
+func NewTyped[T any](options Options) *TypedToken[T] {
+	return &TypedToken[T]{JwtToken: NewWithOptions(options)}
+}
+
+// Generate 生成 Token，data为T类型时平铺到jwt payload，否则同JwtToken.Generate
+func (m *TypedToken[T]) Generate(ctx context.Context, userKey string, data any) (token string, err error) {
+	if claims, ok := data.(T); ok {
+		return m.GenerateClaims(ctx, userKey, claims)
+	}
+	return m.JwtToken.Generate(ctx, userKey, data)
+}
+
+// GenerateClaims 生成 Token，声明平铺到jwt payload，标准声明优先
+func (m *TypedToken[T]) GenerateClaims(ctx context.Context, userKey string, claims T) (token string, err error) {
+	if userKey == "" {
+		err = gerror.NewCode(gcode.CodeMissingParameter, gtoken.MsgErrUserKeyEmpty)
+		return
+	}
+	payload, err := flatClaims(claims)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	registered := m.registeredClaims(userKey, now)
+	// userKey写入标准声明sub
+	registered.Subject = userKey
+	// 自定义声明不包含Now字段，纳秒签发时间用于用户注销判断
+	registered.IssuedAt = jwt.NewNumericDate(now)
+	registeredPayload, err := flatClaims(registered)
+	if err != nil {
+		return
+	}
+	for k, v := range registeredPayload {
+		payload[k] = v
+	}
+	payload[KeyClaimIatNano] = now.UnixNano()
+
+	if token, err = m.signClaims(jwt.MapClaims(payload)); err != nil {
+		return
+	}
+	if m.track {
+		jwtClaims := &JwtClaims{
+			JwtData:          &JwtData{UserKey: userKey, Data: claims},
+			RegisteredClaims: registered,
+			IssuedNano:       now.UnixNano(),
+		}
+		if err = m.saveTrack(ctx, token, jwtClaims); err != nil {
+			return "", gerror.WrapCode(gcode.CodeInternalError, err)
+		}
+	}
+	return
+}
+
+// ParseToken 通过token获取userKey，data为T类型声明
+func (m *TypedToken[T]) ParseToken(ctx context.Context, token string) (userKey string, data any, err error) {
+	userKey, claims, err := m.ParseClaims(ctx, token)
+	if err != nil {
+		return
+	}
+	return userKey, claims, nil
+}
+
+// ParseClaims 通过token获取userKey及T类型声明
+func (m *TypedToken[T]) ParseClaims(ctx context.Context, token string) (userKey string, claims T, err error) {
+	if token == "" {
+		err = gerror.NewCode(gcode.CodeMissingParameter, gtoken.MsgErrTokenEmpty)
+		return
+	}
+	jwtClaims, payload, err := m.parsePayload(ctx, token)
+	if err != nil {
+		return
+	}
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		err = gerror.WrapCode(gcode.CodeInvalidParameter, err)
+		return
+	}
+	return jwtClaims.UserKey, claims, nil
+}
+
+// GetClaims 通过userKey获取token及T类型声明，需启用跟踪模式
+func (m *TypedToken[T]) GetClaims(ctx context.Context, userKey string) (token string, claims T, err error) {
+	if token, _, err = m.Get(ctx, userKey); err != nil {
+		return
+	}
+	_, claims, err = m.ParseClaims(ctx, token)
+	return
+}
+
+// flatClaims 声明转换为map，数字保持原始精度
+func flatClaims(claims any) (payload map[string]any, err error) {
+	content, err := json.Marshal(claims)
+	if err != nil {
+		return
+	}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	if err = decoder.Decode(&payload); err != nil || payload == nil {
+		return nil, gerror.NewCode(gcode.CodeInvalidParameter, MsgErrClaims)
+	}
+	return
+}
@@ -0,0 +1,125 @@
+package gtoken_jwt_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goflyfox/gtoken-jwt/v2"
+	"github.com/goflyfox/gtoken/v2/gtoken"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/stretchr/testify/assert"
+)
+
+type testClaims struct {
+	Roles    []string `json:"roles"`
+	TenantId string   `json:"tenant_id"`
+	OrgId    int64    `json:"org_id"`
+}
+
+func TestTypedToken(t *testing.T) {
+	ctx := gctx.New()
+	userKey := "testUser"
+	claims := testClaims{Roles: []string{"admin"}, TenantId: "t1", OrgId: 9007199254740993}
+
+	gfToken := gtoken_jwt.NewTyped[testClaims](gtoken_jwt.Options{Issuer: "gtoken"})
+	token, err := gfToken.GenerateClaims(ctx, userKey, claims)
+	assert.NoError(t, err)
+
+	// 声明平铺到payload
+	payloadBytes, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[1])
+	assert.NoError(t, err)
+	assert.Contains(t, string(payloadBytes), `"org_id":9007199254740993`)
+	payload := map[string]any{}
+	assert.NoError(t, json.Unmarshal(payloadBytes, &payload))
+	assert.Equal(t, []any{"admin"}, payload["roles"])
+	assert.Equal(t, "t1", payload["tenant_id"])
+	assert.Equal(t, "gtoken", payload["iss"])
+	assert.Contains(t, payload, "jti")
+	assert.Contains(t, payload, "iat")
+	assert.NotContains(t, payload, "Data")
+	assert.NotContains(t, payload, "Now")
+	assert.Contains(t, payload, "iat_ns")
+	// userKey使用标准声明sub
+	assert.Equal(t, userKey, payload["sub"])
+	assert.NotContains(t, payload, "UserKey")
+
+	userKey2, claims2, err := gfToken.ParseClaims(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, userKey, userKey2)
+	assert.Equal(t, claims, claims2)
+
+	// 通过gtoken.Token接口使用
+	var tokenIface gtoken.Token = gfToken
+	token, err = tokenIface.Generate(ctx, userKey, claims)
+	assert.NoError(t, err)
+	u, err := tokenIface.Validate(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, userKey, u)
+	_, claims3, err := gtoken.ParseTokenAs[testClaims](ctx, tokenIface, token)
+	assert.NoError(t, err)
+	assert.Equal(t, claims, claims3)
+}
+
+func TestTypedTokenTrack(t *testing.T) {
+	ctx := gctx.New()
+	userKey := "testUser"
+	claims := testClaims{Roles: []string{"user"}, TenantId: "t2"}
+
+	gfToken := gtoken_jwt.NewTyped[testClaims](gtoken_jwt.Options{
+		Options: gtoken.Options{
+			Timeout:     3000,
+			MaxRefresh:  500,
+			CachePreKey: "GTokenJwtTyped:",
+		},
+		Track:    true,
+		Denylist: true,
+	})
+	token1, err := gfToken.GenerateClaims(ctx, userKey, claims)
+	assert.NoError(t, err)
+	token, claims2, err := gfToken.GetClaims(ctx, userKey)
+	assert.NoError(t, err)
+	assert.Equal(t, token1, token)
+	assert.Equal(t, claims, claims2)
+
+	// 续签保留自定义声明
+	time.Sleep(600 * time.Millisecond)
+	_, err = gfToken.Validate(ctx, token1)
+	assert.NoError(t, err)
+	token2, claims2, err := gfToken.GetClaims(ctx, userKey)
+	assert.NoError(t, err)
+	assert.NotEqual(t, token1, token2)
+	assert.Equal(t, claims, claims2)
+
+	// 注销用户
+	assert.NoError(t, gfToken.Destroy(ctx, userKey))
+	_, err = gfToken.Validate(ctx, token2)
+	assert.Error(t, err)
+}
+
+func TestTypedTokenDenylist(t *testing.T) {
+	ctx := gctx.New()
+	userKey := "testUser"
+	claims := testClaims{Roles: []string{"user"}}
+	gfToken := gtoken_jwt.NewTyped[testClaims](gtoken_jwt.Options{
+		Options:  gtoken.Options{CachePreKey: "GTokenJwtTypedDeny:"},
+		Denylist: true,
+	})
+
+	// 注销前签发的token失效，包括同一秒内签发的token
+	token, err := gfToken.GenerateClaims(ctx, userKey, claims)
+	assert.NoError(t, err)
+	assert.NoError(t, gfToken.Destroy(ctx, userKey))
+	_, err = gfToken.Validate(ctx, token)
+	assert.Equal(t, gtoken.CodeTokenRevoked, gerror.Code(err))
+
+	// 注销后立即登录，签发的token有效
+	token, err = gfToken.GenerateClaims(ctx, userKey, claims)
+	assert.NoError(t, err)
+	u, err := gfToken.Validate(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, userKey, u)
+}
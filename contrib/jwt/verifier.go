@@ -0,0 +1,378 @@
+package gtoken_jwt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/goflyfox/gtoken/v2/gtoken"
+	"github.com/gogf/gf/v2/container/gvar"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/os/gfile"
+	"github.com/gogf/gf/v2/util/gconv"
+	"github.com/golang-jwt/jwt/v5"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultJwksRefresh    = 10 * 60 * 1000 // JWKS刷新间隔（毫秒）
+	DefaultJwksMinRefresh = 10 * 1000      // kid未找到及刷新失败时最小刷新间隔（毫秒）
+	DefaultUserKeyClaim   = "sub"
+
+	MsgErrJwksEmpty = "jwks file or url not configured"
+)
+
+// VerifierOptions 外部token验证配置
+type VerifierOptions struct {
+	gtoken.Options
+	// JwksFile 本地JWKS文件路径
+	JwksFile string
+	// JwksUrl JWKS地址，JwksFile为空时使用
+	JwksUrl string
+	// JwksRefresh JWKS刷新间隔（毫秒） 默认10分钟
+	JwksRefresh int64
+	// UserKeyClaim 作为userKey的声明 默认sub
+	UserKeyClaim string
+	// Issuer 签发者iss，配置后验证签发者一致
+	Issuer string
+	// Audience 接收者aud，配置后验证token接收者包含其中之一
+	Audience []string
+	// Leeway 验证exp、nbf、iat时允许的时钟偏差（毫秒）
+	Leeway int64
+}
+
+func (o *VerifierOptions) String() string {
+	return fmt.Sprintf("VerifierOptions{%s"+
+		", JwksFile:%s, JwksUrl:%s, JwksRefresh:%d, UserKeyClaim:%s, Issuer:%s, Audience:%v, Leeway:%d"+
+		"}", o.Options.String(), o.JwksFile, o.JwksUrl, o.JwksRefresh, o.UserKeyClaim, o.Issuer, o.Audience, o.Leeway)
+}
+
+// JwtVerifier 验证外部签发的jwt，密钥来自JWKS文件或地址
+// 仅支持Validate、ParseToken，用于复用gtoken.Middleware认证
+type JwtVerifier struct {
+	Options         VerifierOptions
+	mu              sync.RWMutex
+	keys            []JSONWebKey
+	refreshTime     int64 // 最后刷新成功时间（毫秒）
+	attemptTime     int64 // 最后刷新时间，含失败（毫秒）
+	failures        int   // 连续刷新失败次数
+	lastMissRefresh int64 // kid未找到时最后刷新时间（毫秒）
+	call            *refreshCall
+}
+
+// refreshCall 进行中的JWKS刷新，并发调用共享同一次请求
+type refreshCall struct {
+	done chan struct{}
+	err  error
+}
+
+func NewVerifierByConfig() gtoken.Token {
+	var options *VerifierOptions
+	ctx := gctx.New()
+	err := g.Cfg().MustGet(ctx, "gToken").Struct(&options)
+	if err != nil {
+		panic("options init fail")
+	}
+	if options == nil {
+		panic("options config not configured")
+	}
+	return NewVerifier(*options)
+}
+
+// NewVerifier 创建外部token验证对象
+// 未配置JwksFile、JwksUrl时panic；首次加载失败时在验证时重试
+func NewVerifier(options VerifierOptions) *JwtVerifier {
+	if options.JwksFile == "" && options.JwksUrl == "" {
+		panic(MsgErrJwksEmpty)
+	}
+	if options.JwksRefresh == 0 {
+		options.JwksRefresh = DefaultJwksRefresh
+	}
+	if options.UserKeyClaim == "" {
+		options.UserKeyClaim = DefaultUserKeyClaim
+	}
+	ctx := gctx.New()
+	verifier := &JwtVerifier{Options: options}
+	if err := verifier.refresh(ctx); err != nil {
+		g.Log().Warning(ctx, "jwks load fail", err)
+	}
+	g.Log().Debug(ctx, "verifier options", options.String())
+	return verifier
+}
+
+// Validate 验证 Token
+func (m *JwtVerifier) Validate(ctx context.Context, token string) (userKey string, err error) {
+	userKey, _, err = m.ParseToken(ctx, token)
+	return
+}
+
+// ParseToken 通过token获取userKey，data为token全部声明
+func (m *JwtVerifier) ParseToken(ctx context.Context, token string) (userKey string, data any, err error) {
+	if token == "" {
+		err = gerror.NewCode(gcode.CodeMissingParameter, gtoken.MsgErrTokenEmpty)
+		return
+	}
+
+	claims := jwt.MapClaims{}
+	jwtToken, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		return m.keyFunc(ctx, token)
+	}, m.parserOptions()...)
+	if err != nil {
+		return "", nil, err
+	}
+	if !jwtToken.Valid {
+		err = gerror.NewCode(gcode.CodeMissingParameter, gtoken.MsgErrValidate)
+		return
+	}
+	if len(m.Options.Audience) > 0 {
+		audience, _ := claims.GetAudience()
+		if !slices.ContainsFunc(m.Options.Audience, func(aud string) bool {
+			return slices.Contains(audience, aud)
+		}) {
+			return "", nil, gerror.WrapCode(gcode.CodeInvalidParameter, jwt.ErrTokenInvalidAudience)
+		}
+	}
+	userKey = gconv.String(claims[m.Options.UserKeyClaim])
+	if userKey == "" {
+		err = gerror.NewCode(gcode.CodeInvalidParameter, gtoken.MsgErrValidate)
+		return
+	}
+	return userKey, g.Map(claims), nil
+}
+
+// parserOptions 解析选项，仅接受非对称签名算法
+func (m *JwtVerifier) parserOptions() []jwt.ParserOption {
+	options := []jwt.ParserOption{jwt.WithValidMethods([]string{
+		"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA",
+	})}
+	if m.Options.Issuer != "" {
+		options = append(options, jwt.WithIssuer(m.Options.Issuer))
+	}
+	if m.Options.Leeway > 0 {
+		options = append(options, jwt.WithLeeway(time.Duration(m.Options.Leeway)*time.Millisecond))
+	}
+	return options
+}
+
+// keyFunc 通过kid选择公钥，JWKS过期或kid未找到时刷新
+func (m *JwtVerifier) keyFunc(ctx context.Context, token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	alg := token.Method.Alg()
+
+	nowTime := time.Now().UnixMilli()
+	if m.refreshDue(nowTime) {
+		if err := m.refresh(ctx); err != nil {
+			g.Log().Warning(ctx, "jwks refresh fail", err)
+		}
+	}
+
+	key, ok := m.findKey(kid, alg)
+	if !ok && m.allowMissRefresh(nowTime) {
+		// 签发方轮换密钥，立即刷新
+		if err := m.refresh(ctx); err != nil {
+			g.Log().Warning(ctx, "jwks refresh fail", err)
+		}
+		key, ok = m.findKey(kid, alg)
+	}
+	if !ok {
+		return nil, gerror.NewCodef(gcode.CodeInvalidParameter, "%s: %s", MsgErrKeyNotFound, kid)
+	}
+	return key.PublicKey()
+}
+
+// findKey 查找kid及签名算法匹配的公钥；token未携带kid时匹配第一个可用公钥
+func (m *JwtVerifier) findKey(kid, alg string) (JSONWebKey, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, key := range m.keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		if (kid == "" || key.Kid == kid) && key.supports(alg) {
+			return key, true
+		}
+	}
+	return JSONWebKey{}, false
+}
+
+// refreshDue JWKS已过期且不在失败退避期内
+func (m *JwtVerifier) refreshDue(nowTime int64) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return nowTime > m.refreshTime+m.Options.JwksRefresh && nowTime >= m.attemptTime+m.backoff()
+}
+
+// backoff 连续失败时的刷新退避间隔（毫秒），从DefaultJwksMinRefresh开始倍增，不超过JwksRefresh
+func (m *JwtVerifier) backoff() int64 {
+	if m.failures == 0 {
+		return 0
+	}
+	backoff := int64(DefaultJwksMinRefresh) << min(m.failures-1, 10)
+	return min(backoff, max(m.Options.JwksRefresh, DefaultJwksMinRefresh))
+}
+
+// allowMissRefresh kid未找到时限制刷新频率，避免伪造kid触发大量请求
+func (m *JwtVerifier) allowMissRefresh(nowTime int64) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if nowTime-m.lastMissRefresh < DefaultJwksMinRefresh || nowTime < m.attemptTime+m.backoff() {
+		return false
+	}
+	m.lastMissRefresh = nowTime
+	return true
+}
+
+// refresh 重新加载JWKS，失败时保留原有公钥；并发调用等待同一次加载结果
+func (m *JwtVerifier) refresh(ctx context.Context) error {
+	m.mu.Lock()
+	if call := m.call; call != nil {
+		m.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	call := &refreshCall{done: make(chan struct{})}
+	m.call = call
+	m.mu.Unlock()
+
+	// 加载结果共享给其他调用方，不受发起请求取消影响
+	call.err = m.load(context.WithoutCancel(ctx))
+
+	m.mu.Lock()
+	m.call = nil
+	m.attemptTime = time.Now().UnixMilli()
+	if call.err != nil {
+		m.failures++
+	} else {
+		m.failures = 0
+	}
+	m.mu.Unlock()
+	close(call.done)
+	return call.err
+}
+
+// load 读取JWKS文件或地址，成功时替换公钥
+func (m *JwtVerifier) load(ctx context.Context) error {
+	var content []byte
+	if m.Options.JwksFile != "" {
+		if !gfile.Exists(m.Options.JwksFile) {
+			return gerror.NewCodef(gcode.CodeInvalidConfiguration, "jwks file not exists: %s", m.Options.JwksFile)
+		}
+		content = gfile.GetBytes(m.Options.JwksFile)
+	} else {
+		resp, err := g.Client().Timeout(10*time.Second).Get(ctx, m.Options.JwksUrl)
+		if err != nil {
+			return err
+		}
+		defer resp.Close()
+		if resp.StatusCode != 200 {
+			return gerror.NewCodef(gcode.CodeInternalError, "jwks request fail: %s", resp.Status)
+		}
+		content = resp.ReadAll()
+	}
+
+	var set JSONWebKeySet
+	if err := json.Unmarshal(content, &set); err != nil {
+		return gerror.WrapCode(gcode.CodeInvalidConfiguration, err, "jwks decode fail")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys = set.Keys
+	m.refreshTime = time.Now().UnixMilli()
+	return nil
+}
+
+// GetOptions 获取Options配置
+func (m *JwtVerifier) GetOptions() gtoken.Options {
+	return m.Options.Options
+}
+
+// Generate 外部签发token，不支持生成
+func (m *JwtVerifier) Generate(ctx context.Context, userKey string, data any) (token string, err error) {
+	err = gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+	return
+}
+
+// Get 外部签发token，不支持获取
+func (m *JwtVerifier) Get(ctx context.Context, userKey string) (token string, data any, err error) {
+	err = gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+	return
+}
+
+// Destroy 外部签发token，不支持销毁
+func (m *JwtVerifier) Destroy(ctx context.Context, userKey string) error {
+	return gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+}
+
+// Sessions 外部签发token，不支持会话查询
+func (m *JwtVerifier) Sessions(ctx context.Context, userKey string) (sessions []gtoken.Session, err error) {
+	return nil, gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+}
+
+// DestroySession 外部签发token，不支持销毁
+func (m *JwtVerifier) DestroySession(ctx context.Context, userKey, sessionId string) error {
+	return gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+}
+
+// DestroyToken 外部签发token，不支持销毁
+func (m *JwtVerifier) DestroyToken(ctx context.Context, token string) error {
+	return gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+}
+
+// UpdateData 外部签发token，不支持更新数据
+func (m *JwtVerifier) UpdateData(ctx context.Context, userKey string, data any) error {
+	return gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+}
+
+// UpdateSessionData 外部签发token，不支持更新数据
+func (m *JwtVerifier) UpdateSessionData(ctx context.Context, userKey, sessionId string, data any) error {
+	return gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+}
+
+// Values 外部签发token，不支持会话键值
+func (m *JwtVerifier) Values(ctx context.Context, token string) (values g.Map, version int64, err error) {
+	err = gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+	return
+}
+
+// GetValue 外部签发token，不支持会话键值
+func (m *JwtVerifier) GetValue(ctx context.Context, token, key string) (value *gvar.Var, err error) {
+	return nil, gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+}
+
+// SetValue 外部签发token，不支持会话键值
+func (m *JwtVerifier) SetValue(ctx context.Context, token, key string, value any) (version int64, err error) {
+	err = gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+	return
+}
+
+// SetValueIfVersion 外部签发token，不支持会话键值
+func (m *JwtVerifier) SetValueIfVersion(ctx context.Context, token, key string, value any, version int64) (newVersion int64, err error) {
+	err = gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+	return
+}
+
+// DeleteValue 外部签发token，不支持会话键值
+func (m *JwtVerifier) DeleteValue(ctx context.Context, token, key string) (version int64, err error) {
+	err = gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+	return
+}
+
+// GeneratePair 外部签发token，不支持生成
+func (m *JwtVerifier) GeneratePair(ctx context.Context, userKey string, data any) (pair gtoken.TokenPair, err error) {
+	err = gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+	return
+}
+
+// Refresh 外部签发token，不支持刷新
+func (m *JwtVerifier) Refresh(ctx context.Context, refreshToken string) (pair gtoken.TokenPair, err error) {
+	err = gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+	return
+}
@@ -0,0 +1,229 @@
+package gtoken_jwt_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/goflyfox/gtoken-jwt/v2"
+	"github.com/goflyfox/gtoken/v2/gtoken"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/os/gfile"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifier(t *testing.T) {
+	ctx := gctx.New()
+	userKey := "testUser"
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.NoError(t, err)
+	rsaPem, _ := pemKeys(t, rsaKey, &rsaKey.PublicKey)
+	ecPem, _ := pemKeys(t, ecKey, &ecKey.PublicKey)
+
+	// 外部签发方
+	issuer := gtoken_jwt.NewWithOptions(gtoken_jwt.Options{
+		SigningMethod: "RS256",
+		Keys:          []gtoken_jwt.Key{{Kid: "k1", PrivateKey: rsaPem}},
+		Issuer:        "idp",
+		Audience:      []string{"api"},
+		Subject:       true,
+	})
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(issuer.Jwks())
+	}))
+	defer server.Close()
+
+	verifier := gtoken_jwt.NewVerifier(gtoken_jwt.VerifierOptions{
+		JwksUrl:  server.URL,
+		Issuer:   "idp",
+		Audience: []string{"api"},
+	})
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	token, err := issuer.Generate(ctx, userKey, g.Map{"a": "1"})
+	assert.NoError(t, err)
+	u, err := verifier.Validate(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, userKey, u)
+	u, data, err := verifier.ParseToken(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, userKey, u)
+	assert.Equal(t, "idp", g.NewVar(data).Map()["iss"])
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	// 签发方轮换密钥，kid未找到时刷新JWKS
+	assert.NoError(t, issuer.AddKey(gtoken_jwt.Key{Kid: "k2", SigningMethod: "ES384", PrivateKey: ecPem}))
+	assert.NoError(t, issuer.SetActiveKey("k2"))
+	token2, err := issuer.Generate(ctx, userKey, nil)
+	assert.NoError(t, err)
+	u, err = verifier.Validate(ctx, token2)
+	assert.NoError(t, err)
+	assert.Equal(t, userKey, u)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	// 未知kid限制刷新频率
+	forgedToken := jwt.NewWithClaims(jwt.SigningMethodES384, jwt.MapClaims{"sub": userKey, "iss": "idp", "aud": "api"})
+	forgedToken.Header["kid"] = "unknown"
+	forged, err := forgedToken.SignedString(ecKey)
+	assert.NoError(t, err)
+	_, err = verifier.Validate(ctx, forged)
+	assert.Error(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	// 仅接受非对称算法
+	hsToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": userKey, "iss": "idp", "aud": "api"}).
+		SignedString([]byte("secret"))
+	assert.NoError(t, err)
+	_, err = verifier.Validate(ctx, hsToken)
+	assert.Error(t, err)
+
+	// 签发者、接收者不一致
+	other := gtoken_jwt.NewWithOptions(gtoken_jwt.Options{
+		SigningMethod: "RS256",
+		Keys:          []gtoken_jwt.Key{{Kid: "k1", PrivateKey: rsaPem}},
+		Issuer:        "idp",
+		Audience:      []string{"admin"},
+		Subject:       true,
+	})
+	token3, err := other.Generate(ctx, userKey, nil)
+	assert.NoError(t, err)
+	_, err = verifier.Validate(ctx, token3)
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidAudience)
+
+	// 不支持生成及销毁
+	_, err = verifier.Generate(ctx, userKey, nil)
+	assert.Equal(t, gcode.CodeNotSupported, gerror.Code(err))
+	assert.Equal(t, gcode.CodeNotSupported, gerror.Code(verifier.Destroy(ctx, userKey)))
+}
+
+func TestVerifierFile(t *testing.T) {
+	ctx := gctx.New()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	rsaPem, _ := pemKeys(t, rsaKey, &rsaKey.PublicKey)
+	issuer := gtoken_jwt.NewWithOptions(gtoken_jwt.Options{
+		SigningMethod: "PS256",
+		PrivateKey:    rsaPem,
+	})
+	content, err := json.Marshal(issuer.Jwks())
+	assert.NoError(t, err)
+	jwksFile := gfile.Temp("gtoken-jwt-test", "jwks.json")
+	defer gfile.Remove(gfile.Dir(jwksFile))
+	assert.NoError(t, gfile.PutBytes(jwksFile, content))
+
+	// 自定义userKey声明
+	verifier := gtoken_jwt.NewVerifier(gtoken_jwt.VerifierOptions{
+		JwksFile:     jwksFile,
+		UserKeyClaim: "UserKey",
+	})
+	token, err := issuer.Generate(ctx, "testUser", nil)
+	assert.NoError(t, err)
+	u, err := verifier.Validate(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, "testUser", u)
+
+	// 中间件复用
+	var _ gtoken.Token = verifier
+	assert.Panics(t, func() {
+		gtoken_jwt.NewVerifier(gtoken_jwt.VerifierOptions{})
+	})
+}
+
+func TestVerifierRefreshFail(t *testing.T) {
+	ctx := gctx.New()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	rsaPem, _ := pemKeys(t, rsaKey, &rsaKey.PublicKey)
+	issuer := gtoken_jwt.NewWithOptions(gtoken_jwt.Options{
+		SigningMethod: "RS256",
+		Keys:          []gtoken_jwt.Key{{Kid: "k1", PrivateKey: rsaPem}},
+		Subject:       true,
+	})
+	token, err := issuer.Generate(ctx, "testUser", nil)
+	assert.NoError(t, err)
+
+	// JWKS地址不可用，失败后退避，不在每次验证时请求
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	verifier := gtoken_jwt.NewVerifier(gtoken_jwt.VerifierOptions{JwksUrl: server.URL})
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := verifier.Validate(ctx, token)
+			assert.Error(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestVerifierRefreshShared(t *testing.T) {
+	ctx := gctx.New()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	rsaPem, _ := pemKeys(t, rsaKey, &rsaKey.PublicKey)
+	issuer := gtoken_jwt.NewWithOptions(gtoken_jwt.Options{
+		SigningMethod: "RS256",
+		Keys:          []gtoken_jwt.Key{{Kid: "k1", PrivateKey: rsaPem}},
+		Subject:       true,
+	})
+	token, err := issuer.Generate(ctx, "testUser", nil)
+	assert.NoError(t, err)
+
+	var requests int32
+	var slow atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if slow.Load() {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(issuer.Jwks())
+	}))
+	defer server.Close()
+
+	verifier := gtoken_jwt.NewVerifier(gtoken_jwt.VerifierOptions{JwksUrl: server.URL, JwksRefresh: 1})
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	// JWKS过期，并发验证共享同一次刷新
+	slow.Store(true)
+	time.Sleep(5 * time.Millisecond)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u, err := verifier.Validate(ctx, token)
+			assert.NoError(t, err)
+			assert.Equal(t, "testUser", u)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
@@ -0,0 +1,85 @@
+package gtoken_paseto
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/os/gfile"
+)
+
+const (
+	MsgErrPurpose       = "paseto purpose not support"
+	MsgErrLocalKey      = "paseto local key must be 32 bytes"
+	MsgErrLocalKeyEmpty = "paseto local key not configured"
+	MsgErrPrivateKey    = "paseto private key error"
+	MsgErrPublicKey     = "paseto public key error"
+	MsgErrTokenHeader   = "paseto token header error"
+	MsgErrTokenFormat   = "paseto token format error"
+	MsgErrTokenFooter   = "paseto token footer mismatch"
+	MsgErrTokenAuth     = "paseto token authentication failed"
+	MsgErrClaims        = "paseto token claims error"
+)
+
+// loadPublicKeys 读取public模式Ed25519密钥，公钥为空时从私钥获取
+func loadPublicKeys(options Options) (privateKey ed25519.PrivateKey, publicKey ed25519.PublicKey, err error) {
+	privatePem := options.PrivateKey
+	if len(privatePem) == 0 && options.PrivateKeyFile != "" {
+		privatePem = gfile.GetBytes(options.PrivateKeyFile)
+	}
+	publicPem := options.PublicKey
+	if len(publicPem) == 0 && options.PublicKeyFile != "" {
+		publicPem = gfile.GetBytes(options.PublicKeyFile)
+	}
+
+	if len(privatePem) > 0 {
+		if privateKey, err = parsePrivateKey(privatePem); err != nil {
+			return
+		}
+		publicKey = privateKey.Public().(ed25519.PublicKey)
+	}
+	if len(publicPem) > 0 {
+		if publicKey, err = parsePublicKey(publicPem); err != nil {
+			return
+		}
+	}
+	if publicKey == nil {
+		err = gerror.NewCode(gcode.CodeInvalidConfiguration, MsgErrPublicKey)
+	}
+	return
+}
+
+// parsePrivateKey 解析PKCS8 PEM格式Ed25519私钥
+func parsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, gerror.NewCode(gcode.CodeInvalidConfiguration, MsgErrPrivateKey)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, gerror.WrapCode(gcode.CodeInvalidConfiguration, err, MsgErrPrivateKey)
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, gerror.NewCode(gcode.CodeInvalidConfiguration, MsgErrPrivateKey)
+	}
+	return privateKey, nil
+}
+
+// parsePublicKey 解析PKIX PEM格式Ed25519公钥
+func parsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, gerror.NewCode(gcode.CodeInvalidConfiguration, MsgErrPublicKey)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, gerror.WrapCode(gcode.CodeInvalidConfiguration, err, MsgErrPublicKey)
+	}
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, gerror.NewCode(gcode.CodeInvalidConfiguration, MsgErrPublicKey)
+	}
+	return publicKey, nil
+}
@@ -0,0 +1,47 @@
+package gtoken_paseto
+
+import (
+	"fmt"
+	"github.com/goflyfox/gtoken/v2/gtoken"
+)
+
+const (
+	PurposeLocal  = "local"  // v4.local 对称加密
+	PurposePublic = "public" // v4.public Ed25519签名
+
+	DefaultPurpose = PurposeLocal
+)
+
+// Options paseto配置项，兼容gtoken配置项
+type Options struct {
+	gtoken.Options
+	// Purpose token用途，默认local：local为对称加密token，public为Ed25519签名token（内容明文可读）
+	Purpose string
+	// LocalKey local模式32字节密钥，必须配置且不能使用默认密钥；New创建时使用EncryptKey
+	LocalKey []byte
+	// PrivateKey public模式PEM格式Ed25519私钥，用于生成token；仅验证token的服务可不配置
+	PrivateKey []byte
+	// PublicKey public模式PEM格式Ed25519公钥，用于验证token；为空时从私钥获取
+	PublicKey []byte
+	// PrivateKeyFile PEM格式私钥文件路径，PrivateKey为空时读取
+	PrivateKeyFile string
+	// PublicKeyFile PEM格式公钥文件路径，PublicKey为空时读取
+	PublicKeyFile string
+	// Footer token尾部，明文可读且参与认证，验证时必须一致
+	Footer string
+	// Implicit 隐式断言，不写入token但参与认证，验证时必须一致
+	Implicit string
+	// Issuer 签发者iss，配置后验证token签发者必须一致
+	Issuer string
+	// Audience 接收者aud，配置后验证token接收者必须一致
+	Audience string
+	// Leeway 验证exp、nbf、iat时允许的时钟偏差（毫秒）
+	Leeway int64
+}
+
+func (o *Options) String() string {
+	return fmt.Sprintf("Options{%s"+
+		", Purpose:%s, PrivateKeyFile:%s, PublicKeyFile:%s, Footer:%s, Issuer:%s, Audience:%s, Leeway:%d"+
+		"}", o.Options.String(), o.Purpose, o.PrivateKeyFile, o.PublicKeyFile, o.Footer,
+		o.Issuer, o.Audience, o.Leeway)
+}
@@ -0,0 +1,305 @@
+package gtoken_paseto
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"github.com/goflyfox/gtoken/v2/gtoken"
+	"github.com/gogf/gf/v2/container/gvar"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/util/guid"
+	"time"
+)
+
+const (
+	DefaultShortTimeout = 5 * 1000
+)
+
+// PasetoToken paseto结构体
+// 版本及用途由配置固定，验证时不从token中读取算法，避免算法混淆
+type PasetoToken struct {
+	Options       gtoken.Options
+	PasetoOptions Options
+	localKey      []byte             // local模式密钥
+	privateKey    ed25519.PrivateKey // public模式签名私钥
+	publicKey     ed25519.PublicKey  // public模式验证公钥
+}
+
+// Claims paseto标准声明，时间为RFC3339格式
+type Claims struct {
+	Issuer     string `json:"iss,omitempty"`
+	Subject    string `json:"sub"` // 用户标识
+	Audience   string `json:"aud,omitempty"`
+	Expiration string `json:"exp"`
+	NotBefore  string `json:"nbf"`
+	IssuedAt   string `json:"iat"`
+	TokenId    string `json:"jti"`
+	Data       any    `json:"data,omitempty"` // 数据
+}
+
+func NewByConfig() gtoken.Token {
+	var options *Options
+	ctx := gctx.New()
+	err := g.Cfg().MustGet(ctx, "gToken").Struct(&options)
+	if err != nil {
+		panic("options init fail")
+	}
+	if options == nil {
+		panic("options config not configured")
+	}
+	return NewWithOptions(*options)
+}
+
+// New 使用v4.local创建token，EncryptKey作为local模式密钥
+// 说明：此token为无状态token，不支持刷新，不支持多端登录，仅适用于短期或者一次性token的使用场景
+func New(options gtoken.Options) gtoken.Token {
+	return NewWithOptions(Options{Options: options, LocalKey: options.EncryptKey})
+}
+
+// NewWithOptions 通过paseto配置项创建token
+// 密钥配置错误时panic
+func NewWithOptions(options Options) *PasetoToken {
+	if options.Timeout == 0 {
+		options.Timeout = DefaultShortTimeout
+	}
+	if options.Purpose == "" {
+		options.Purpose = DefaultPurpose
+	}
+
+	gfToken := &PasetoToken{
+		Options:       options.Options,
+		PasetoOptions: options,
+	}
+	switch options.Purpose {
+	case PurposeLocal:
+		// 必须显式配置密钥，不使用公开的默认密钥
+		gfToken.localKey = options.LocalKey
+		if len(gfToken.localKey) == 0 || string(gfToken.localKey) == gtoken.DefaultEncryptKey {
+			panic(gerror.NewCode(gcode.CodeInvalidConfiguration, MsgErrLocalKeyEmpty))
+		}
+		if len(gfToken.localKey) != v4LocalKeySize {
+			panic(gerror.NewCode(gcode.CodeInvalidConfiguration, MsgErrLocalKey))
+		}
+	case PurposePublic:
+		privateKey, publicKey, err := loadPublicKeys(options)
+		if err != nil {
+			panic(err)
+		}
+		gfToken.privateKey, gfToken.publicKey = privateKey, publicKey
+	default:
+		panic(gerror.NewCodef(gcode.CodeInvalidConfiguration, "%s: %s", MsgErrPurpose, options.Purpose))
+	}
+	g.Log().Debug(gctx.New(), "token options", options.String())
+	return gfToken
+}
+
+// Generate 生成 Token
+func (m *PasetoToken) Generate(ctx context.Context, userKey string, data any) (token string, err error) {
+	if userKey == "" {
+		err = gerror.NewCode(gcode.CodeMissingParameter, gtoken.MsgErrUserKeyEmpty)
+		return
+	}
+	now := time.Now()
+	claims := &Claims{
+		Issuer:     m.PasetoOptions.Issuer,
+		Subject:    userKey,
+		Audience:   m.PasetoOptions.Audience,
+		Expiration: formatTime(now.Add(time.Duration(m.Options.Timeout) * time.Millisecond)),
+		NotBefore:  formatTime(now),
+		IssuedAt:   formatTime(now),
+		TokenId:    guid.S(),
+		Data:       data,
+	}
+	message, err := json.Marshal(claims)
+	if err != nil {
+		err = gerror.WrapCode(gcode.CodeInvalidParameter, err)
+		return
+	}
+	return m.seal(message)
+}
+
+// seal 按用途加密或签名
+func (m *PasetoToken) seal(message []byte) (token string, err error) {
+	footer, implicit := []byte(m.PasetoOptions.Footer), []byte(m.PasetoOptions.Implicit)
+	if m.PasetoOptions.Purpose == PurposePublic {
+		if m.privateKey == nil {
+			err = gerror.NewCode(gcode.CodeInvalidConfiguration, MsgErrPrivateKey)
+			return
+		}
+		return v4PublicSign(m.privateKey, message, footer, implicit), nil
+	}
+	return v4LocalEncrypt(m.localKey, message, footer, implicit)
+}
+
+// open 按用途解密或验证签名
+func (m *PasetoToken) open(token string) ([]byte, error) {
+	footer, implicit := []byte(m.PasetoOptions.Footer), []byte(m.PasetoOptions.Implicit)
+	if m.PasetoOptions.Purpose == PurposePublic {
+		return v4PublicVerify(m.publicKey, token, footer, implicit)
+	}
+	return v4LocalDecrypt(m.localKey, token, footer, implicit)
+}
+
+// Validate 验证 Token
+func (m *PasetoToken) Validate(ctx context.Context, token string) (userKey string, err error) {
+	if token == "" {
+		err = gerror.NewCode(gcode.CodeMissingParameter, gtoken.MsgErrTokenEmpty)
+		return
+	}
+	claims, err := m.parse(token)
+	if err != nil {
+		return
+	}
+	return claims.Subject, nil
+}
+
+// Get paseto为无状态token，不支持通过userKey获取token
+func (m *PasetoToken) Get(ctx context.Context, userKey string) (token string, data any, err error) {
+	err = gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+	return
+}
+
+// ParseToken 通过token获取userKey
+func (m *PasetoToken) ParseToken(ctx context.Context, token string) (userKey string, data any, err error) {
+	if token == "" {
+		err = gerror.NewCode(gcode.CodeMissingParameter, gtoken.MsgErrTokenEmpty)
+		return
+	}
+	claims, err := m.parse(token)
+	if err != nil {
+		return
+	}
+	return claims.Subject, claims.Data, nil
+}
+
+// parse 解密或验证签名后解析声明，验证exp、nbf、iat、iss、aud
+func (m *PasetoToken) parse(token string) (claims *Claims, err error) {
+	message, err := m.open(token)
+	if err != nil {
+		return
+	}
+	if err = json.Unmarshal(message, &claims); err != nil || claims == nil || claims.Subject == "" {
+		return nil, gerror.NewCode(gcode.CodeInvalidParameter, MsgErrClaims)
+	}
+	if err = m.validateClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// validateClaims 验证标准声明
+func (m *PasetoToken) validateClaims(claims *Claims) error {
+	now := time.Now()
+	leeway := time.Duration(m.PasetoOptions.Leeway) * time.Millisecond
+
+	expiration, err := time.Parse(time.RFC3339, claims.Expiration)
+	if err != nil {
+		return gerror.WrapCodef(gcode.CodeInvalidParameter, err, "%s: exp", MsgErrClaims)
+	}
+	if now.After(expiration.Add(leeway)) {
+		return gerror.NewCode(gtoken.CodeTokenExpired, gtoken.MsgErrTokenExpired)
+	}
+	if claims.NotBefore != "" {
+		notBefore, err := time.Parse(time.RFC3339, claims.NotBefore)
+		if err != nil || now.Add(leeway).Before(notBefore) {
+			return gerror.NewCodef(gcode.CodeInvalidParameter, "%s: nbf", MsgErrClaims)
+		}
+	}
+	if claims.IssuedAt != "" {
+		issuedAt, err := time.Parse(time.RFC3339, claims.IssuedAt)
+		if err != nil || now.Add(leeway).Before(issuedAt) {
+			return gerror.NewCodef(gcode.CodeInvalidParameter, "%s: iat", MsgErrClaims)
+		}
+	}
+	if m.PasetoOptions.Issuer != "" && claims.Issuer != m.PasetoOptions.Issuer {
+		return gerror.NewCodef(gcode.CodeInvalidParameter, "%s: iss", MsgErrClaims)
+	}
+	if m.PasetoOptions.Audience != "" && claims.Audience != m.PasetoOptions.Audience {
+		return gerror.NewCodef(gcode.CodeInvalidParameter, "%s: aud", MsgErrClaims)
+	}
+	return nil
+}
+
+// formatTime RFC3339格式时间，保留纳秒避免短超时被截断
+func formatTime(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
+
+// Destroy paseto为无状态token，不支持销毁，token在超时前仍然有效
+func (m *PasetoToken) Destroy(ctx context.Context, userKey string) error {
+	return gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+}
+
+// Sessions paseto为无状态token，不支持会话查询
+func (m *PasetoToken) Sessions(ctx context.Context, userKey string) (sessions []gtoken.Session, err error) {
+	return nil, gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+}
+
+// DestroySession paseto为无状态token，不支持销毁单个会话
+func (m *PasetoToken) DestroySession(ctx context.Context, userKey, sessionId string) error {
+	return gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+}
+
+// DestroyToken paseto为无状态token，不支持销毁单个token
+func (m *PasetoToken) DestroyToken(ctx context.Context, token string) error {
+	return gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+}
+
+// UpdateData paseto为无状态token，不支持更新数据
+func (m *PasetoToken) UpdateData(ctx context.Context, userKey string, data any) error {
+	return gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+}
+
+// UpdateSessionData paseto为无状态token，不支持更新数据
+func (m *PasetoToken) UpdateSessionData(ctx context.Context, userKey, sessionId string, data any) error {
+	return gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+}
+
+// Values paseto为无状态token，不支持会话键值
+func (m *PasetoToken) Values(ctx context.Context, token string) (values g.Map, version int64, err error) {
+	err = gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+	return
+}
+
+// GetValue paseto为无状态token，不支持会话键值
+func (m *PasetoToken) GetValue(ctx context.Context, token, key string) (value *gvar.Var, err error) {
+	return nil, gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+}
+
+// SetValue paseto为无状态token，不支持会话键值
+func (m *PasetoToken) SetValue(ctx context.Context, token, key string, value any) (version int64, err error) {
+	err = gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+	return
+}
+
+// SetValueIfVersion paseto为无状态token，不支持会话键值
+func (m *PasetoToken) SetValueIfVersion(ctx context.Context, token, key string, value any, version int64) (newVersion int64, err error) {
+	err = gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+	return
+}
+
+// DeleteValue paseto为无状态token，不支持会话键值
+func (m *PasetoToken) DeleteValue(ctx context.Context, token, key string) (version int64, err error) {
+	err = gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+	return
+}
+
+// GeneratePair paseto token暂不支持双token
+func (m *PasetoToken) GeneratePair(ctx context.Context, userKey string, data any) (pair gtoken.TokenPair, err error) {
+	err = gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+	return
+}
+
+// Refresh paseto token暂不支持双token
+func (m *PasetoToken) Refresh(ctx context.Context, refreshToken string) (pair gtoken.TokenPair, err error) {
+	err = gerror.NewCode(gcode.CodeNotSupported, gtoken.MsgErrNotSupport)
+	return
+}
+
+// GetOptions 获取Options配置
+func (m *PasetoToken) GetOptions() gtoken.Options {
+	return m.Options
+}
@@ -0,0 +1,210 @@
+package gtoken_paseto_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/goflyfox/gtoken-paseto/v2"
+	"github.com/goflyfox/gtoken/v2/gtoken"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/text/gstr"
+	"github.com/gogf/gf/v2/util/gconv"
+	"github.com/stretchr/testify/assert"
+)
+
+var localKey = []byte("abcdefghijklmnopabcdefghijklmnop")
+
+func pemKeys(t *testing.T) (privatePem, publicPem []byte) {
+	public, key, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	privateBytes, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+	publicBytes, err := x509.MarshalPKIXPublicKey(public)
+	assert.NoError(t, err)
+	privatePem = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateBytes})
+	publicPem = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicBytes})
+	return
+}
+
+func TestLocal(t *testing.T) {
+	ctx := gctx.New()
+	userKey := "testUser"
+	data := g.Map{"a": "1"}
+
+	gToken := gtoken_paseto.New(gtoken.Options{EncryptKey: localKey})
+	token, err := gToken.Generate(ctx, userKey, data)
+	assert.NoError(t, err)
+	assert.True(t, gstr.HasPrefix(token, gtoken_paseto.HeaderV4Local))
+	// 内容加密，不可读
+	assert.NotContains(t, token, "eyJ")
+
+	u, err := gToken.Validate(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, userKey, u)
+	u, d, err := gToken.ParseToken(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, userKey, u)
+	assert.Equal(t, "1", gconv.Map(d)["a"])
+
+	// 每次生成不一致
+	token2, err := gToken.Generate(ctx, userKey, data)
+	assert.NoError(t, err)
+	assert.NotEqual(t, token, token2)
+
+	// 篡改
+	tampered := []byte(token)
+	tampered[len(tampered)-5] ^= 1
+	_, err = gToken.Validate(ctx, string(tampered))
+	assert.Error(t, err)
+	// 密钥不一致
+	other := gtoken_paseto.New(gtoken.Options{EncryptKey: []byte("abcdefghijklmnopqrstuvwxyz123456")})
+	_, err = other.Validate(ctx, token)
+	assert.Error(t, err)
+	// 空token及格式错误
+	_, err = gToken.Validate(ctx, "")
+	assert.Error(t, err)
+	_, err = gToken.Validate(ctx, "v4.local.123")
+	assert.Error(t, err)
+	// 密钥长度错误
+	assert.Panics(t, func() {
+		gtoken_paseto.New(gtoken.Options{EncryptKey: []byte("123")})
+	})
+	// 未配置密钥或使用默认密钥
+	assert.Panics(t, func() {
+		gtoken_paseto.New(gtoken.Options{})
+	})
+	assert.Panics(t, func() {
+		gtoken_paseto.NewWithOptions(gtoken_paseto.Options{Options: gtoken.Options{EncryptKey: localKey}})
+	})
+	assert.Panics(t, func() {
+		gtoken_paseto.NewWithOptions(gtoken_paseto.Options{LocalKey: []byte(gtoken.DefaultEncryptKey)})
+	})
+}
+
+func TestPublic(t *testing.T) {
+	ctx := gctx.New()
+	userKey := "testUser"
+	privatePem, publicPem := pemKeys(t)
+
+	issuer := gtoken_paseto.NewWithOptions(gtoken_paseto.Options{
+		Purpose:    gtoken_paseto.PurposePublic,
+		PrivateKey: privatePem,
+	})
+	token, err := issuer.Generate(ctx, userKey, "data")
+	assert.NoError(t, err)
+	assert.True(t, gstr.HasPrefix(token, gtoken_paseto.HeaderV4Public))
+
+	// 仅配置公钥的服务可验证，不可签发
+	verifier := gtoken_paseto.NewWithOptions(gtoken_paseto.Options{
+		Purpose:   gtoken_paseto.PurposePublic,
+		PublicKey: publicPem,
+	})
+	u, d, err := verifier.ParseToken(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, userKey, u)
+	assert.Equal(t, "data", d)
+	_, err = verifier.Generate(ctx, userKey, nil)
+	assert.Error(t, err)
+
+	// 其他密钥签名
+	otherPem, _ := pemKeys(t)
+	other := gtoken_paseto.NewWithOptions(gtoken_paseto.Options{
+		Purpose:    gtoken_paseto.PurposePublic,
+		PrivateKey: otherPem,
+	})
+	_, err = other.Validate(ctx, token)
+	assert.Error(t, err)
+
+	// 用途由配置决定，public token不能通过local验证，反之亦然
+	local := gtoken_paseto.New(gtoken.Options{EncryptKey: localKey})
+	_, err = local.Validate(ctx, token)
+	assert.Error(t, err)
+	localToken, err := local.Generate(ctx, userKey, nil)
+	assert.NoError(t, err)
+	_, err = verifier.Validate(ctx, localToken)
+	assert.Error(t, err)
+
+	// 未配置密钥及用途错误
+	assert.Panics(t, func() {
+		gtoken_paseto.NewWithOptions(gtoken_paseto.Options{Purpose: gtoken_paseto.PurposePublic})
+	})
+	assert.Panics(t, func() {
+		gtoken_paseto.NewWithOptions(gtoken_paseto.Options{Purpose: "v2"})
+	})
+}
+
+func TestFooterImplicit(t *testing.T) {
+	ctx := gctx.New()
+	userKey := "testUser"
+	options := gtoken_paseto.Options{LocalKey: localKey, Footer: `{"kid":"k1"}`, Implicit: "tenant-1"}
+
+	gToken := gtoken_paseto.NewWithOptions(options)
+	token, err := gToken.Generate(ctx, userKey, nil)
+	assert.NoError(t, err)
+	assert.Len(t, gstr.Split(token, "."), 4)
+	_, err = gToken.Validate(ctx, token)
+	assert.NoError(t, err)
+
+	// footer不一致
+	other := options
+	other.Footer = `{"kid":"k2"}`
+	_, err = gtoken_paseto.NewWithOptions(other).Validate(ctx, token)
+	assert.Error(t, err)
+	// 隐式断言不一致
+	other = options
+	other.Implicit = "tenant-2"
+	_, err = gtoken_paseto.NewWithOptions(other).Validate(ctx, token)
+	assert.Error(t, err)
+}
+
+func TestClaims(t *testing.T) {
+	ctx := gctx.New()
+	userKey := "testUser"
+
+	// 超时
+	{
+		gToken := gtoken_paseto.New(gtoken.Options{EncryptKey: localKey, Timeout: 200})
+		token, err := gToken.Generate(ctx, userKey, nil)
+		assert.NoError(t, err)
+		_, err = gToken.Validate(ctx, token)
+		assert.NoError(t, err)
+		time.Sleep(300 * time.Millisecond)
+		_, err = gToken.Validate(ctx, token)
+		assert.Equal(t, gtoken.CodeTokenExpired, gerror.Code(err))
+
+		// 时钟偏差
+		leeway := gtoken_paseto.NewWithOptions(gtoken_paseto.Options{LocalKey: localKey, Leeway: 1000})
+		_, err = leeway.Validate(ctx, token)
+		assert.NoError(t, err)
+	}
+	// 签发者及接收者
+	{
+		gToken := gtoken_paseto.NewWithOptions(gtoken_paseto.Options{LocalKey: localKey, Issuer: "gtoken", Audience: "app"})
+		token, err := gToken.Generate(ctx, userKey, nil)
+		assert.NoError(t, err)
+		_, err = gToken.Validate(ctx, token)
+		assert.NoError(t, err)
+
+		_, err = gtoken_paseto.NewWithOptions(gtoken_paseto.Options{LocalKey: localKey, Issuer: "other"}).Validate(ctx, token)
+		assert.Equal(t, gcode.CodeInvalidParameter, gerror.Code(err))
+		_, err = gtoken_paseto.NewWithOptions(gtoken_paseto.Options{LocalKey: localKey, Audience: "other"}).Validate(ctx, token)
+		assert.Equal(t, gcode.CodeInvalidParameter, gerror.Code(err))
+	}
+	// 无状态token不支持的方法
+	{
+		gToken := gtoken_paseto.New(gtoken.Options{EncryptKey: localKey})
+		_, _, err := gToken.Get(ctx, userKey)
+		assert.Equal(t, gcode.CodeNotSupported, gerror.Code(err))
+		err = gToken.DestroyToken(ctx, "token")
+		assert.Equal(t, gcode.CodeNotSupported, gerror.Code(err))
+		err = gToken.Destroy(ctx, userKey)
+		assert.Equal(t, gcode.CodeNotSupported, gerror.Code(err))
+	}
+}
@@ -0,0 +1,36 @@
+package gtoken_paseto
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 官方测试向量 https://github.com/paseto-standard/test-vectors
+func TestV4Vectors(t *testing.T) {
+	message := []byte(`{"data":"this is a secret message","exp":"2022-01-01T00:00:00+00:00"}`)
+	// 4-E-1
+	{
+		key, _ := hex.DecodeString("707172737475767778797a7b7c7d7e7f808182838485868788898a8b8c8d8e8f")
+		nonce := make([]byte, 32)
+		token, err := v4LocalEncryptWithNonce(key, nonce, message, nil, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "v4.local.AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAQAr68PS4AXe7If_ZgesdkUMvSwscFlAl1pk5HC0e8kApeaqMfGo_7OpBnwJOAbY9V7WU6abu74MmcUE8YWAiaArVI8XJ5hOb_4v9RmDkneN0S92dx0OW4pgy7omxgf3S8c3LlQg", token)
+		decrypted, err := v4LocalDecrypt(key, token, nil, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, message, decrypted)
+	}
+	// 4-S-1
+	{
+		seed, _ := hex.DecodeString("b4cbfb43df4ce210727d953e4a713307fa19bb7d9f85041438d9e11b942a3774")
+		privateKey := ed25519.NewKeyFromSeed(seed)
+		signed := []byte(`{"data":"this is a signed message","exp":"2022-01-01T00:00:00+00:00"}`)
+		token := v4PublicSign(privateKey, signed, nil, nil)
+		assert.Equal(t, "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9bg_XBBzds8lTZShVlwwKSgeKpLT3yukTw6JUz3W4h_ExsQV-P0V54zemZDcAxFaSeef1QlXEFtkqxT1ciiQEDA", token)
+		verified, err := v4PublicVerify(privateKey.Public().(ed25519.PublicKey), token, nil, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, signed, verified)
+	}
+}
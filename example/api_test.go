@@ -294,17 +294,32 @@ func TestMultiLogin(t *testing.T) {
 
 	t.Log("token2:" + token2)
 
+	// 每次登录均生成独立会话Token
+	if token1 == token2 {
+		t.Error("error:", "token same ")
+	}
+
 	MultiLogin, err := g.Cfg().Get(ctx, "gToken.MultiLogin")
 	if err != nil {
 		panic(err)
 	}
+	client := g.Client()
+	client.SetHeader("Authorization", "Bearer "+token1)
+	content := client.PostContent(ctx, TestURL+"/system/user")
+	var respData backend.Resp
+	err = json.Unmarshal([]byte(content), &respData)
+	if err != nil {
+		t.Error("error:", err)
+	}
 	if MultiLogin.Bool() {
-		if token1 != token2 {
-			t.Error("error:", "token not same ")
+		// 多端登录，旧会话仍然有效
+		if respData.Code != gcode.CodeOK.Code() {
+			t.Error("error:", "token1 invalid:", respData)
 		}
 	} else {
-		if token1 == token2 {
-			t.Error("error:", "token same ")
+		// 单端登录，旧会话被剔除
+		if respData.Code == gcode.CodeOK.Code() {
+			t.Error("error:", "token1 still valid:", respData)
 		}
 	}
 }
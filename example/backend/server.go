@@ -50,6 +50,14 @@ func InitRouter(s *ghttp.Server) {
 		middlewareAuth := gtoken.NewDefaultMiddleware(gToken)
 		// token校验失败后的返回方法
 		middlewareAuth.ResFun = func(r *ghttp.Request, err error) {
+			if gtoken.IsKickedOut(err) {
+				r.Response.WriteJson(g.Map{
+					"code":    401,
+					"message": "您的账号已在其他设备登录",
+					"data":    []interface{}{},
+				})
+				return
+			}
 			r.Response.WriteJson(g.Map{
 				"code":    500, // 默认: gcode.CodeBusinessValidationFailed.Code()
 				"message": "身份认证过期，请重新登录:" + err.Error(),
@@ -67,9 +75,11 @@ func InitRouter(s *ghttp.Server) {
 		// 获取登录扩展属性
 		group.ALL("/system/data", func(r *ghttp.Request) {
 			// 获取登陆信息
+			// 默认不保存原始token，仅返回数据
 			_, data, err := gToken.Get(r.Context(), r.GetCtxVar(gtoken.KeyUserKey).String())
-			if err != nil {
+			if err != nil && !gtoken.IsTokenNotStored(err) {
 				r.Response.WriteJson(RespError(err))
+				return
 			}
 			r.Response.WriteJson(RespSuccess(data))
 		})
@@ -80,6 +90,7 @@ func InitRouter(s *ghttp.Server) {
 			_, data, err := gToken.ParseToken(r.Context(), token)
 			if err != nil {
 				r.Response.WriteJson(RespError(err))
+				return
 			}
 			r.Response.WriteJson(RespSuccess(data))
 		})
@@ -92,6 +103,24 @@ func InitRouter(s *ghttp.Server) {
 		group.ALL("/system/user/info", func(r *ghttp.Request) {
 			r.Response.WriteJson(RespSuccess("system user info"))
 		})
+		// 获取当前用户全部登录会话
+		group.ALL("/user/sessions", func(r *ghttp.Request) {
+			sessions, err := gToken.Sessions(r.Context(), r.GetCtxVar(gtoken.KeyUserKey).String())
+			if err != nil {
+				r.Response.WriteJson(RespError(err))
+				r.ExitAll()
+			}
+			r.Response.WriteJson(RespSuccess(sessions))
+		})
+		// 注销指定登录会话
+		group.ALL("/user/session/logout", func(r *ghttp.Request) {
+			err := gToken.DestroySession(r.Context(), r.GetCtxVar(gtoken.KeyUserKey).String(), r.Get(gtoken.KeySessionId).String())
+			if err != nil {
+				r.Response.WriteJson(RespError(err))
+				r.ExitAll()
+			}
+			r.Response.WriteJson(RespSuccess("session logout"))
+		})
 		group.ALL("/user/logout", func(r *ghttp.Request) {
 			// 登出销毁Token
 			_ = gToken.Destroy(ctx, r.GetCtxVar(gtoken.KeyUserKey).String())
@@ -107,8 +136,8 @@ func InitRouter(s *ghttp.Server) {
 			r.Response.WriteJson(RespFail("账号或密码错误."))
 			r.ExitAll()
 		}
-		// 认证成功调用Generate生成Token
-		token, err := gToken.Generate(ctx, username, g.Map{"username": username})
+		// 认证成功调用Generate生成Token，传入请求上下文记录登录设备信息
+		token, err := gToken.Generate(r.Context(), username, g.Map{"username": username})
 		if err != nil {
 			r.Response.WriteJson(RespError(err))
 			r.ExitAll()
@@ -1,18 +1,29 @@
 package gtoken
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"errors"
+	"github.com/gogf/gf/v2/container/gvar"
 	"github.com/gogf/gf/v2/encoding/gjson"
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/gogf/gf/v2/os/gcache"
 	"github.com/gogf/gf/v2/os/gctx"
 	"github.com/gogf/gf/v2/os/gfile"
+	"github.com/gogf/gf/v2/os/gmlock"
 	"github.com/gogf/gf/v2/text/gstr"
 	"github.com/gogf/gf/v2/util/gconv"
+	"github.com/gogf/gf/v2/util/grand"
+	"io"
 	"time"
 )
 
+const (
+	cacheEncryptPrefix = "gtenc1:" // 加密缓存数据前缀
+)
+
 // Cache 缓存接口
 type Cache interface {
 	// Set 设置缓冲
@@ -23,6 +34,32 @@ type Cache interface {
 	Remove(ctx context.Context, cacheKey string) error
 }
 
+// UpdateCache 支持原子更新的缓存接口
+type UpdateCache interface {
+	Cache
+	// Update 原子更新缓存，f入参为当前缓存（不存在为nil），返回nil时删除缓存
+	Update(ctx context.Context, cacheKey string, f func(cacheValue g.Map) (g.Map, error)) error
+}
+
+// UpdateCacheValue 原子更新缓存，cache未实现UpdateCache时退化为Get/Set
+func UpdateCacheValue(ctx context.Context, cache Cache, cacheKey string, f func(cacheValue g.Map) (g.Map, error)) error {
+	if updateCache, ok := cache.(UpdateCache); ok {
+		return updateCache.Update(ctx, cacheKey, f)
+	}
+	cacheValue, err := cache.Get(ctx, cacheKey)
+	if err != nil {
+		return err
+	}
+	cacheValue, err = f(cacheValue)
+	if err != nil {
+		return err
+	}
+	if cacheValue == nil {
+		return cache.Remove(ctx, cacheKey)
+	}
+	return cache.Set(ctx, cacheKey, cacheValue)
+}
+
 // DefaultCache 默认缓存
 type DefaultCache struct {
 	Cache *gcache.Cache
@@ -32,6 +69,8 @@ type DefaultCache struct {
 	PreKey string
 	// 超时时间 默认10天（毫秒）
 	Timeout int64
+	// 缓存数据加密key（16、24或32字节），配置后写入的json使用AES-GCM加密，为空时明文存储
+	DataKey []byte
 }
 
 func NewDefaultCache(mode int8, preKey string, timeout int64) *DefaultCache {
@@ -52,22 +91,14 @@ func NewDefaultCache(mode int8, preKey string, timeout int64) *DefaultCache {
 }
 
 // Set 设置缓存
+// gcache、gfile模式与Update使用同一互斥锁，避免并发Update覆盖写入结果
 func (c *DefaultCache) Set(ctx context.Context, cacheKey string, cacheValue g.Map) error {
-	if cacheValue == nil {
-		return errors.New(MsgErrDataEmpty)
-	}
-	value, err := gjson.Encode(cacheValue)
-	if err != nil {
-		return err
-	}
-	err = c.Cache.Set(ctx, c.PreKey+cacheKey, string(value), gconv.Duration(c.Timeout)*time.Millisecond)
-	if err != nil {
-		return err
-	}
-	if c.Mode == CacheModeFile {
-		c.writeFileCache(ctx)
+	if c.Mode != CacheModeRedis {
+		lockKey := c.PreKey + cacheKey
+		gmlock.Lock(lockKey)
+		defer gmlock.Unlock(lockKey)
 	}
-	return nil
+	return c.set(ctx, cacheKey, cacheValue)
 }
 
 // Get 获取缓存
@@ -79,11 +110,64 @@ func (c *DefaultCache) Get(ctx context.Context, cacheKey string) (g.Map, error)
 	if dataVar.IsNil() {
 		return nil, nil
 	}
-	return dataVar.Map(), nil
+	return c.decodeValue(c.PreKey+cacheKey, dataVar)
 }
 
 // Remove 删除缓存
+// gcache、gfile模式与Update使用同一互斥锁，避免删除后被并发Update重新写入
 func (c *DefaultCache) Remove(ctx context.Context, cacheKey string) error {
+	if c.Mode != CacheModeRedis {
+		lockKey := c.PreKey + cacheKey
+		gmlock.Lock(lockKey)
+		defer gmlock.Unlock(lockKey)
+	}
+	return c.remove(ctx, cacheKey)
+}
+
+// Update 原子更新缓存
+// gcache、gfile模式通过进程内互斥锁保证原子性；gredis模式通过脚本比较并写入，并发修改时重试，保证集群内原子性
+func (c *DefaultCache) Update(ctx context.Context, cacheKey string, f func(cacheValue g.Map) (g.Map, error)) error {
+	if c.Mode == CacheModeRedis {
+		return c.updateRedis(ctx, cacheKey, f)
+	}
+
+	lockKey := c.PreKey + cacheKey
+	gmlock.Lock(lockKey)
+	defer gmlock.Unlock(lockKey)
+
+	cacheValue, err := c.Get(ctx, cacheKey)
+	if err != nil {
+		return err
+	}
+	cacheValue, err = f(cacheValue)
+	if err != nil {
+		return err
+	}
+	if cacheValue == nil {
+		return c.remove(ctx, cacheKey)
+	}
+	return c.set(ctx, cacheKey, cacheValue)
+}
+
+func (c *DefaultCache) set(ctx context.Context, cacheKey string, cacheValue g.Map) error {
+	if cacheValue == nil {
+		return errors.New(MsgErrDataEmpty)
+	}
+	value, err := c.encodeValue(c.PreKey+cacheKey, cacheValue)
+	if err != nil {
+		return err
+	}
+	err = c.Cache.Set(ctx, c.PreKey+cacheKey, value, gconv.Duration(c.Timeout)*time.Millisecond)
+	if err != nil {
+		return err
+	}
+	if c.Mode == CacheModeFile {
+		c.writeFileCache(ctx)
+	}
+	return nil
+}
+
+func (c *DefaultCache) remove(ctx context.Context, cacheKey string) error {
 	_, err := c.Cache.Remove(ctx, c.PreKey+cacheKey)
 	if c.Mode == CacheModeFile {
 		c.writeFileCache(ctx)
@@ -91,6 +175,125 @@ func (c *DefaultCache) Remove(ctx context.Context, cacheKey string) error {
 	return err
 }
 
+// updateRedis 读取缓存后通过脚本比较并写入，key被并发修改时重试
+// redis连接来自连接池，不能保证WATCH/MULTI/EXEC在同一连接执行，使用单条EVAL保证原子性
+func (c *DefaultCache) updateRedis(ctx context.Context, cacheKey string, f func(cacheValue g.Map) (g.Map, error)) error {
+	redisKey := c.PreKey + cacheKey
+	for i := 0; i < DefaultUpdateRetry; i++ {
+		ok, err := c.updateRedisOnce(ctx, redisKey, f)
+		if err != nil || ok {
+			return err
+		}
+		// 随机退避，避免并发更新同时重试
+		time.Sleep(time.Duration(grand.N(1, 10*(i+1))) * time.Millisecond)
+	}
+	return errors.New(MsgErrUpdateConflict)
+}
+
+// redisCompareAndSet 缓存值与读取时一致才写入，新值为空时删除
+const redisCompareAndSet = `
+local current = redis.call('GET', KEYS[1])
+if current == false then current = '' end
+if current ~= ARGV[1] then return 0 end
+if ARGV[2] == '' then
+	redis.call('DEL', KEYS[1])
+else
+	redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+end
+return 1`
+
+func (c *DefaultCache) updateRedisOnce(ctx context.Context, redisKey string, f func(cacheValue g.Map) (g.Map, error)) (bool, error) {
+	dataVar, err := g.Redis().Do(ctx, "GET", redisKey)
+	if err != nil {
+		return false, err
+	}
+	var (
+		cacheValue g.Map
+		oldValue   string
+		newValue   string
+	)
+	if !dataVar.IsNil() {
+		oldValue = dataVar.String()
+		if cacheValue, err = c.decodeValue(redisKey, dataVar); err != nil {
+			return false, err
+		}
+	}
+	if cacheValue, err = f(cacheValue); err != nil {
+		return false, err
+	}
+	if cacheValue != nil {
+		if newValue, err = c.encodeValue(redisKey, cacheValue); err != nil {
+			return false, err
+		}
+	}
+	result, err := g.Redis().Do(ctx, "EVAL", redisCompareAndSet, 1, redisKey, oldValue, newValue, c.Timeout)
+	if err != nil {
+		return false, err
+	}
+	return result.Int() == 1, nil
+}
+
+// CheckCacheEncryptKey 校验缓存数据加密key长度，且与token加密key均不同
+func CheckCacheEncryptKey(dataKey []byte, encryptKeys ...[]byte) error {
+	if _, err := newAead(dataKey); err != nil {
+		return err
+	}
+	for _, encryptKey := range encryptKeys {
+		if bytes.Equal(dataKey, encryptKey) {
+			return errors.New(MsgErrCacheEncryptKey)
+		}
+	}
+	return nil
+}
+
+// encodeValue 缓存数据json编码，配置DataKey时加密，缓存key作为附加认证数据，防止数据在key之间替换
+func (c *DefaultCache) encodeValue(key string, cacheValue g.Map) (string, error) {
+	value, err := gjson.Encode(cacheValue)
+	if err != nil {
+		return "", err
+	}
+	if len(c.DataKey) == 0 {
+		return string(value), nil
+	}
+	aead, err := newAead(c.DataKey)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, value, []byte(key))
+	return cacheEncryptPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// decodeValue 缓存数据解码，兼容配置DataKey前写入的明文数据
+func (c *DefaultCache) decodeValue(key string, dataVar *gvar.Var) (g.Map, error) {
+	value := dataVar.String()
+	if !gstr.HasPrefix(value, cacheEncryptPrefix) {
+		return dataVar.Map(), nil
+	}
+	if len(c.DataKey) == 0 {
+		return nil, errors.New(MsgErrCacheDecrypt)
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(value[len(cacheEncryptPrefix):])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAead(c.DataKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New(MsgErrCacheDecrypt)
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(key))
+	if err != nil {
+		return nil, errors.New(MsgErrCacheDecrypt)
+	}
+	return gconv.Map(string(plaintext)), nil
+}
+
 func (c *DefaultCache) writeFileCache(ctx context.Context) {
 	fileName := gstr.Replace(c.PreKey, ":", "_") + CacheModeFileDat
 	file := gfile.Temp(fileName)
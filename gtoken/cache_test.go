@@ -1,10 +1,17 @@
 package gtoken_test
 
 import (
+	"github.com/alicebob/miniredis"
 	"github.com/goflyfox/gtoken/v2/gtoken"
+	_ "github.com/gogf/gf/contrib/nosql/redis/v2"
+	"github.com/gogf/gf/v2/database/gredis"
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/os/gfile"
+	"github.com/gogf/gf/v2/util/gconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -73,3 +80,217 @@ func TestDefaultCache(t *testing.T) {
 		})
 	}
 }
+
+var (
+	miniRedisOnce sync.Once
+	miniRedis     bool
+)
+
+// useRedis 未配置redis时启动miniredis，redis模式测试无需本地服务；返回是否使用miniredis
+func useRedis(t *testing.T) bool {
+	miniRedisOnce.Do(func() {
+		if _, ok := gredis.GetConfig(); ok {
+			return
+		}
+		server, err := miniredis.Run()
+		if err != nil {
+			t.Fatal("miniredis start fail:", err)
+		}
+		gredis.SetConfig(&gredis.Config{Address: server.Addr()})
+		miniRedis = true
+	})
+	return miniRedis
+}
+
+func TestDefaultCacheUpdate(t *testing.T) {
+	modes := map[string]int8{
+		"cache": gtoken.CacheModeCache,
+		"redis": gtoken.CacheModeRedis,
+		"file":  gtoken.CacheModeFile,
+	}
+	for name, mode := range modes {
+		t.Run(name, func(t *testing.T) {
+			// miniredis执行脚本时不加锁，并发更新需要redis服务
+			if mode == gtoken.CacheModeRedis && useRedis(t) {
+				t.Skip("miniredis scripts are not atomic")
+			}
+			ctx := gctx.New()
+			cache := gtoken.NewDefaultCache(mode, "GTokenCacheUpdate:", gtoken.DefaultTimeout)
+			// 并发更新不丢失
+			var wg sync.WaitGroup
+			for i := 0; i < 50; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					err := cache.Update(ctx, "counter", func(cacheValue g.Map) (g.Map, error) {
+						if cacheValue == nil {
+							cacheValue = g.Map{}
+						}
+						cacheValue["num"] = gconv.Int(cacheValue["num"]) + 1
+						return cacheValue, nil
+					})
+					assert.NoError(t, err)
+				}()
+			}
+			wg.Wait()
+			data, err := cache.Get(ctx, "counter")
+			assert.NoError(t, err)
+			assert.Equal(t, 50, gconv.Int(data["num"]))
+
+			// 返回nil删除缓存
+			err = cache.Update(ctx, "counter", func(cacheValue g.Map) (g.Map, error) {
+				return nil, nil
+			})
+			assert.NoError(t, err)
+			data, err = cache.Get(ctx, "counter")
+			assert.NoError(t, err)
+			assert.Nil(t, data)
+		})
+	}
+}
+
+func TestDefaultCacheRemoveDuringUpdate(t *testing.T) {
+	ctx := gctx.New()
+	for _, mode := range []int8{gtoken.CacheModeCache, gtoken.CacheModeFile} {
+		cache := gtoken.NewDefaultCache(mode, "GTokenCacheRemove:", gtoken.DefaultTimeout)
+		assert.NoError(t, cache.Set(ctx, "session", g.Map{"num": 1}))
+
+		var (
+			updating = make(chan struct{})
+			release  = make(chan struct{})
+			removed  = make(chan error)
+		)
+		go func() {
+			err := cache.Update(ctx, "session", func(cacheValue g.Map) (g.Map, error) {
+				close(updating)
+				<-release
+				cacheValue["num"] = 2
+				return cacheValue, nil
+			})
+			assert.NoError(t, err)
+		}()
+		<-updating
+		go func() {
+			removed <- cache.Remove(ctx, "session")
+		}()
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		assert.NoError(t, <-removed)
+
+		// 删除在更新完成后执行，不会被更新重新写入
+		data, err := cache.Get(ctx, "session")
+		assert.NoError(t, err)
+		assert.Nil(t, data)
+	}
+}
+
+func TestDefaultCacheRedisConflict(t *testing.T) {
+	useRedis(t)
+	ctx := gctx.New()
+	cache := gtoken.NewDefaultCache(gtoken.CacheModeRedis, "GTokenCacheConflict:", gtoken.DefaultTimeout)
+	cache.DataKey = []byte("0123456789abcdef0123456789abcdef")
+	assert.NoError(t, cache.Set(ctx, "alice", g.Map{"num": 0}))
+
+	// 更新期间key被修改，事务取消后重新读取并重试
+	calls := 0
+	err := cache.Update(ctx, "alice", func(cacheValue g.Map) (g.Map, error) {
+		calls++
+		if calls == 1 {
+			assert.NoError(t, cache.Set(ctx, "alice", g.Map{"num": 10}))
+		}
+		cacheValue["num"] = gconv.Int(cacheValue["num"]) + 1
+		return cacheValue, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	data, err := cache.Get(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, 11, gconv.Int(data["num"]))
+
+	// 持续冲突时超过重试次数返回错误
+	calls = 0
+	err = cache.Update(ctx, "alice", func(cacheValue g.Map) (g.Map, error) {
+		calls++
+		assert.NoError(t, cache.Set(ctx, "alice", g.Map{"num": calls}))
+		return cacheValue, nil
+	})
+	assert.EqualError(t, err, gtoken.MsgErrUpdateConflict)
+	assert.Equal(t, gtoken.DefaultUpdateRetry, calls)
+
+	// 编码失败时不写入
+	err = cache.Update(ctx, "alice", func(cacheValue g.Map) (g.Map, error) {
+		cacheValue["ch"] = make(chan int)
+		return cacheValue, nil
+	})
+	assert.Error(t, err)
+	err = cache.Update(ctx, "alice", func(cacheValue g.Map) (g.Map, error) {
+		return nil, nil
+	})
+	assert.NoError(t, err)
+	data, err = cache.Get(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Nil(t, data)
+}
+
+func TestDefaultCacheEncrypt(t *testing.T) {
+	useRedis(t)
+	ctx := gctx.New()
+	dataKey := []byte("0123456789abcdef0123456789abcdef")
+	for _, mode := range []int8{gtoken.CacheModeCache, gtoken.CacheModeRedis, gtoken.CacheModeFile} {
+		cache := gtoken.NewDefaultCache(mode, "GTokenCacheEncrypt:", gtoken.DefaultTimeout)
+		// 配置前写入的明文数据仍可读取
+		assert.NoError(t, cache.Set(ctx, "plain", g.Map{"name": "plain"}))
+		cache.DataKey = dataKey
+
+		assert.NoError(t, cache.Set(ctx, "alice", g.Map{"name": "alice", "phone": "13800000000"}))
+		raw, err := cache.Cache.Get(ctx, "GTokenCacheEncrypt:alice")
+		assert.NoError(t, err)
+		assert.NotContains(t, raw.String(), "13800000000")
+		data, err := cache.Get(ctx, "alice")
+		assert.NoError(t, err)
+		assert.Equal(t, "13800000000", data["phone"])
+
+		data, err = cache.Get(ctx, "plain")
+		assert.NoError(t, err)
+		assert.Equal(t, "plain", data["name"])
+
+		// 原子更新
+		err = cache.Update(ctx, "alice", func(cacheValue g.Map) (g.Map, error) {
+			cacheValue["age"] = 18
+			return cacheValue, nil
+		})
+		assert.NoError(t, err)
+		data, err = cache.Get(ctx, "alice")
+		assert.NoError(t, err)
+		assert.Equal(t, 18, gconv.Int(data["age"]))
+
+		// 数据在key之间替换
+		raw, err = cache.Cache.Get(ctx, "GTokenCacheEncrypt:alice")
+		assert.NoError(t, err)
+		assert.NoError(t, cache.Cache.Set(ctx, "GTokenCacheEncrypt:bob", raw.String(), 0))
+		_, err = cache.Get(ctx, "bob")
+		assert.Error(t, err)
+
+		// 密钥错误
+		cache.DataKey = []byte("abcdef0123456789abcdef0123456789")
+		_, err = cache.Get(ctx, "alice")
+		assert.Error(t, err)
+		cache.DataKey = nil
+		_, err = cache.Get(ctx, "alice")
+		assert.Error(t, err)
+	}
+
+	// 文件缓存不包含明文
+	cache := gtoken.NewDefaultCache(gtoken.CacheModeFile, "GTokenCacheEncryptFile:", gtoken.DefaultTimeout)
+	cache.DataKey = dataKey
+	assert.NoError(t, cache.Set(ctx, "alice", g.Map{"phone": "13800000000"}))
+	content := gfile.GetContents(gfile.Temp("GTokenCacheEncryptFile_" + gtoken.CacheModeFileDat))
+	assert.NotEmpty(t, content)
+	assert.NotContains(t, content, "13800000000")
+	// 重新加载文件缓存
+	reload := gtoken.NewDefaultCache(gtoken.CacheModeFile, "GTokenCacheEncryptFile:", gtoken.DefaultTimeout)
+	reload.DataKey = dataKey
+	data, err := reload.Get(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, "13800000000", data["phone"])
+}
@@ -2,22 +2,40 @@ package gtoken
 
 import (
 	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
 	"errors"
+	"fmt"
 	"github.com/gogf/gf/v2/crypto/gaes"
-	"github.com/gogf/gf/v2/crypto/gmd5"
 	"github.com/gogf/gf/v2/encoding/gbase64"
 	"github.com/gogf/gf/v2/text/gstr"
-	"github.com/gogf/gf/v2/util/grand"
+	"hash/crc32"
+	"io"
+)
+
+const (
+	TokenVersion1 byte = 1 // AES-GCM加密，字段长度前缀编码
+	TokenVersion2 byte = 2 // 版本1基础上头部加入密钥ID
+
+	tokenNonceSize   = 12
+	tokenTagSize     = 16
+	legacyRandStrLen = 32
+
+	tokenPrefixSeparator = "_"
+	tokenChecksumLen     = 8 // crc32十六进制长度
 )
 
 // Encoder 定义编码器接口
 type Encoder interface {
-	Encode(ctx context.Context, userKey string) (token string, err error)
+	Encode(ctx context.Context, userKey, sessionId string) (token string, err error)
 }
 
 // Decoder 定义解码器接口
 type Decoder interface {
-	Decrypt(ctx context.Context, token string) (userKey string, err error)
+	Decrypt(ctx context.Context, token string) (userKey, sessionId string, err error)
 }
 
 // Codec 组合编码器和解码器接口
@@ -26,12 +44,31 @@ type Codec interface {
 	Decoder
 }
 
+// Reissuer 支持密钥轮换的编解码器实现，用于判断token是否需要使用当前密钥重新签发
+type Reissuer interface {
+	NeedReissue(ctx context.Context, token string) bool
+}
+
+// CodecKey 解密密钥
+type CodecKey struct {
+	Kid string // 密钥ID
+	Key []byte // 密钥，16、24或32字节
+}
+
 // DefaultCodec 默认编解码
 type DefaultCodec struct {
-	// 编码分隔符
+	// 编码分隔符，仅用于解析v2.0.x旧格式token
 	Delimiter string
 	// 加密key
 	EncryptKey []byte
+	// 加密key ID，写入token头部
+	Kid string
+	// 仅用于解密的旧密钥，密钥轮换后已签发的token仍可解密
+	DecryptKeys []CodecKey
+	// 是否拒绝旧格式token，默认false兼容v2.0.x签发的token
+	DisableLegacy bool
+	// token前缀，配置后token格式为：前缀_URL安全base64_crc32，便于密钥扫描工具识别；为空时为标准base64
+	Prefix string
 }
 
 func NewDefaultCodec(delimiter string, encryptKey []byte) *DefaultCodec {
@@ -42,39 +79,251 @@ func NewDefaultCodec(delimiter string, encryptKey []byte) *DefaultCodec {
 }
 
 // Encode token加密方法
-func (c *DefaultCodec) Encode(ctx context.Context, userKey string) (token string, err error) {
+// 格式：版本号(1) + 密钥ID(长度前缀编码) + 随机数(12) + AES-GCM密文(userKey、sessionId长度前缀编码) + 认证标签(16)
+func (c *DefaultCodec) Encode(ctx context.Context, userKey, sessionId string) (token string, err error) {
 	if userKey == "" {
 		return "", errors.New(MsgErrUserKeyEmpty)
 	}
-	// 随机
-	randStr, err := gmd5.Encrypt(grand.Letters(10))
+	if sessionId == "" {
+		return "", errors.New(MsgErrSessionIdEmpty)
+	}
+	aead, err := newAead(c.EncryptKey)
 	if err != nil {
 		return "", err
 	}
-	encryptBeforeStr := userKey + c.Delimiter + randStr
-	encryptByte, err := gaes.Encrypt([]byte(encryptBeforeStr), c.EncryptKey)
-	if err != nil {
+	nonce := make([]byte, tokenNonceSize)
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
 		return "", err
 	}
-	return gbase64.EncodeToString(encryptByte), nil
+	plaintext := appendField(nil, userKey)
+	plaintext = appendField(plaintext, sessionId)
+
+	header := appendField([]byte{TokenVersion2}, c.Kid)
+	buf := make([]byte, 0, len(header)+len(nonce)+len(plaintext)+tokenTagSize)
+	buf = append(append(buf, header...), nonce...)
+	buf = aead.Seal(buf, nonce, plaintext, header)
+	return c.encodeToken(buf), nil
 }
 
 // Decrypt token解密方法
-func (c *DefaultCodec) Decrypt(ctx context.Context, token string) (userKey string, err error) {
+// 认证失败的token直接返回错误，不会进入缓存查询
+func (c *DefaultCodec) Decrypt(ctx context.Context, token string) (userKey, sessionId string, err error) {
 	if token == "" {
-		return "", errors.New(MsgErrTokenEmpty)
+		return "", "", errors.New(MsgErrTokenEmpty)
 	}
-	token64, err := gbase64.Decode([]byte(token))
+	token64, err := c.decodeToken(token)
 	if err != nil {
-		return "", err
+		return "", "", err
+	}
+	userKey, sessionId, err = c.decryptVersioned(token64)
+	if err == nil || c.DisableLegacy {
+		return
 	}
-	decryptStr, err := gaes.Decrypt(token64, c.EncryptKey)
+	// 旧格式token不携带版本号，认证失败时按旧格式解析
+	for _, key := range c.keys() {
+		if userKey, sessionId, legacyErr := c.decryptLegacy(token64, key); legacyErr == nil {
+			return userKey, sessionId, nil
+		}
+	}
+	return "", "", err
+}
+
+// NeedReissue 判断token是否未使用当前密钥加密，需在调用Decrypt验证通过后使用
+func (c *DefaultCodec) NeedReissue(ctx context.Context, token string) bool {
+	data, err := c.decodeToken(token)
 	if err != nil {
-		return "", err
+		return false
+	}
+	// 旧格式及版本1 token均需重新签发
+	kid, header, _, err := parseHeader(data)
+	if err != nil || header[0] != TokenVersion2 {
+		return true
+	}
+	return kid != c.Kid
+}
+
+// encodeToken 编码token，配置前缀时追加crc32校验码
+func (c *DefaultCodec) encodeToken(data []byte) string {
+	if c.Prefix == "" {
+		return gbase64.EncodeToString(data)
+	}
+	body := c.Prefix + tokenPrefixSeparator + base64.RawURLEncoding.EncodeToString(data)
+	return body + tokenPrefixSeparator + checksum(body)
+}
+
+// decodeToken 解码token，带前缀token先校验crc32，校验失败不再解密
+// 配置前缀前签发的标准base64 token仍可解码
+func (c *DefaultCodec) decodeToken(token string) ([]byte, error) {
+	if c.Prefix == "" || !gstr.HasPrefix(token, c.Prefix+tokenPrefixSeparator) {
+		return gbase64.Decode([]byte(token))
+	}
+	bodyLen := len(token) - len(tokenPrefixSeparator) - tokenChecksumLen
+	if bodyLen <= len(c.Prefix)+len(tokenPrefixSeparator) || token[bodyLen:bodyLen+len(tokenPrefixSeparator)] != tokenPrefixSeparator {
+		return nil, errors.New(MsgErrTokenLen)
+	}
+	body := token[:bodyLen]
+	if checksum(body) != token[bodyLen+len(tokenPrefixSeparator):] {
+		return nil, errors.New(MsgErrTokenChecksum)
+	}
+	return base64.RawURLEncoding.DecodeString(body[len(c.Prefix)+len(tokenPrefixSeparator):])
+}
+
+func checksum(s string) string {
+	return fmt.Sprintf("%08x", crc32.ChecksumIEEE([]byte(s)))
+}
+
+// decryptVersioned 解密带版本号token；版本1未记录密钥ID，依次尝试全部密钥
+func (c *DefaultCodec) decryptVersioned(data []byte) (userKey, sessionId string, err error) {
+	kid, header, body, err := parseHeader(data)
+	if err != nil {
+		return
+	}
+	if header[0] == TokenVersion1 {
+		for _, key := range c.keys() {
+			if userKey, sessionId, err = openToken(key, header, body); err == nil {
+				return
+			}
+		}
+		return
+	}
+	key, ok := c.key(kid)
+	if !ok {
+		return "", "", errors.New(MsgErrTokenKey)
+	}
+	return openToken(key, header, body)
+}
+
+// checkKids 校验密钥ID唯一，解密密钥与加密key ID相同时按密钥ID只能选中加密key，已签发token将全部认证失败
+func (c *DefaultCodec) checkKids() error {
+	kids := map[string]struct{}{c.Kid: {}}
+	for _, key := range c.DecryptKeys {
+		if _, ok := kids[key.Kid]; ok {
+			return fmt.Errorf("%s: %q", MsgErrTokenKid, key.Kid)
+		}
+		kids[key.Kid] = struct{}{}
+	}
+	return nil
+}
+
+// key 通过密钥ID获取密钥
+func (c *DefaultCodec) key(kid string) ([]byte, bool) {
+	if kid == c.Kid {
+		return c.EncryptKey, true
+	}
+	for _, key := range c.DecryptKeys {
+		if key.Kid == kid {
+			return key.Key, true
+		}
+	}
+	return nil, false
+}
+
+// keys 全部密钥，当前加密key在前
+func (c *DefaultCodec) keys() [][]byte {
+	keys := [][]byte{c.EncryptKey}
+	for _, key := range c.DecryptKeys {
+		keys = append(keys, key.Key)
+	}
+	return keys
+}
+
+// parseHeader 解析token头部，返回密钥ID、头部（认证附加数据）及随机数+密文
+func parseHeader(data []byte) (kid string, header, body []byte, err error) {
+	if len(data) == 0 {
+		return "", nil, nil, errors.New(MsgErrTokenVersion)
+	}
+	var rest []byte
+	switch data[0] {
+	case TokenVersion1:
+		rest = data[1:]
+	case TokenVersion2:
+		var ok bool
+		if kid, rest, ok = readField(data[1:]); !ok {
+			return "", nil, nil, errors.New(MsgErrTokenLen)
+		}
+	default:
+		return "", nil, nil, errors.New(MsgErrTokenVersion)
+	}
+	if len(rest) < tokenNonceSize+tokenTagSize {
+		return "", nil, nil, errors.New(MsgErrTokenLen)
+	}
+	headerLen := len(data) - len(rest)
+	return kid, data[:headerLen], rest, nil
+}
+
+// openToken 认证并解密token
+func openToken(key, header, body []byte) (userKey, sessionId string, err error) {
+	aead, err := newAead(key)
+	if err != nil {
+		return "", "", err
+	}
+	plaintext, err := aead.Open(nil, body[:tokenNonceSize], body[tokenNonceSize:], header)
+	if err != nil {
+		return "", "", errors.New(MsgErrTokenAuth)
+	}
+	userKey, plaintext, ok := readField(plaintext)
+	if !ok {
+		return "", "", errors.New(MsgErrTokenLen)
+	}
+	sessionId, plaintext, ok = readField(plaintext)
+	if !ok || len(plaintext) != 0 || userKey == "" || sessionId == "" {
+		return "", "", errors.New(MsgErrTokenLen)
 	}
+	return userKey, sessionId, nil
+}
+
+// decryptLegacy 解析v2.0.x旧格式token：userKey + 分隔符 + md5随机串
+// 旧格式不包含会话ID，以随机串作为会话ID；旧格式无认证标签，校验随机串格式以拒绝篡改后的乱码
+func (c *DefaultCodec) decryptLegacy(data, key []byte) (userKey, sessionId string, err error) {
+	decryptStr, err := gaes.Decrypt(data, key)
+	if err != nil {
+		return "", "", err
+	}
+	// 随机串不包含分隔符，从末尾解析，兼容userKey包含分隔符
 	decryptArray := gstr.Split(string(decryptStr), c.Delimiter)
-	if len(decryptArray) < 2 {
-		return "", errors.New(MsgErrTokenLen)
+	size := len(decryptArray)
+	if size < 2 || !isLegacyRandStr(decryptArray[size-1]) {
+		return "", "", errors.New(MsgErrTokenLen)
+	}
+	userKey = gstr.Join(decryptArray[:size-1], c.Delimiter)
+	if userKey == "" {
+		return "", "", errors.New(MsgErrTokenLen)
+	}
+	return userKey, decryptArray[size-1], nil
+}
+
+func isLegacyRandStr(s string) bool {
+	if len(s) != legacyRandStrLen {
+		return false
+	}
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func newAead(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// appendField 长度前缀编码字段
+func appendField(buf []byte, field string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(field)))
+	return append(buf, field...)
+}
+
+// readField 读取长度前缀编码字段
+func readField(buf []byte) (field string, rest []byte, ok bool) {
+	length, n := binary.Uvarint(buf)
+	if n <= 0 || uint64(len(buf)-n) < length {
+		return "", nil, false
 	}
-	return decryptArray[0], nil
+	end := n + int(length)
+	return string(buf[n:end]), buf[end:], true
 }
@@ -0,0 +1,60 @@
+package gtoken
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"github.com/gogf/gf/v2/text/gstr"
+)
+
+// LegacyCodec v2.0.x编解码接口，token中仅包含userKey
+type LegacyCodec interface {
+	Encode(ctx context.Context, userKey string) (token string, err error)
+	Decrypt(ctx context.Context, token string) (userKey string, err error)
+}
+
+// LegacyCodecAdapter 将v2.0.x自定义编解码适配为Codec
+// sessionId十六进制编码后通过分隔符拼接在userKey后一起编码，解码时从最后一个分隔符拆分
+type LegacyCodecAdapter struct {
+	Codec     LegacyCodec
+	Delimiter string
+}
+
+// NewLegacyCodecAdapter 适配v2.0.x自定义编解码，delimiter为空时使用DefaultTokenDelimiter
+func NewLegacyCodecAdapter(codec LegacyCodec, delimiter string) *LegacyCodecAdapter {
+	if delimiter == "" {
+		delimiter = DefaultTokenDelimiter
+	}
+	return &LegacyCodecAdapter{
+		Codec:     codec,
+		Delimiter: delimiter,
+	}
+}
+
+// Encode token加密方法
+func (c *LegacyCodecAdapter) Encode(ctx context.Context, userKey, sessionId string) (token string, err error) {
+	if userKey == "" {
+		return "", errors.New(MsgErrUserKeyEmpty)
+	}
+	if sessionId == "" {
+		return "", errors.New(MsgErrSessionIdEmpty)
+	}
+	return c.Codec.Encode(ctx, userKey+c.Delimiter+hex.EncodeToString([]byte(sessionId)))
+}
+
+// Decrypt token解密方法，升级前签发的token不包含sessionId，返回错误
+func (c *LegacyCodecAdapter) Decrypt(ctx context.Context, token string) (userKey, sessionId string, err error) {
+	value, err := c.Codec.Decrypt(ctx, token)
+	if err != nil {
+		return "", "", err
+	}
+	pos := gstr.PosR(value, c.Delimiter)
+	if pos <= 0 {
+		return "", "", errors.New(MsgErrTokenLen)
+	}
+	sessionIdBytes, err := hex.DecodeString(value[pos+len(c.Delimiter):])
+	if err != nil || len(sessionIdBytes) == 0 {
+		return "", "", errors.New(MsgErrTokenLen)
+	}
+	return value[:pos], string(sessionIdBytes), nil
+}
@@ -1,8 +1,15 @@
 package gtoken_test
 
 import (
+	"context"
 	"github.com/goflyfox/gtoken/v2/gtoken"
+	"github.com/gogf/gf/v2/crypto/gaes"
+	"github.com/gogf/gf/v2/crypto/gmd5"
+	"github.com/gogf/gf/v2/encoding/gbase64"
 	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/util/grand"
+	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -13,8 +20,8 @@ func TestDefaultCodec(t *testing.T) {
 	codec := gtoken.NewDefaultCodec("_", []byte("koi29a83idakguqjq29asd9asd8a7jhq"))
 	ctx := gctx.New()
 	type TestStruct struct {
-		UserKey string
-		Data    string
+		UserKey   string
+		SessionId string
 	}
 
 	tests := []struct {
@@ -25,13 +32,19 @@ func TestDefaultCodec(t *testing.T) {
 	}{
 		{
 			name:           "success",
-			input:          TestStruct{UserKey: "alice"},
+			input:          TestStruct{UserKey: "alice", SessionId: "s1"},
 			wantEncodeErr:  false,
 			wantDecryptErr: false,
 		},
+		{
+			name:           "sessionId nil",
+			input:          TestStruct{UserKey: "alice", SessionId: ""},
+			wantEncodeErr:  true,
+			wantDecryptErr: true,
+		},
 		{
 			name:           "userKey nil",
-			input:          TestStruct{UserKey: ""},
+			input:          TestStruct{UserKey: "", SessionId: "s1"},
 			wantEncodeErr:  true,
 			wantDecryptErr: true,
 		},
@@ -39,7 +52,7 @@ func TestDefaultCodec(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			token, err := codec.Encode(ctx, tt.input.UserKey)
+			token, err := codec.Encode(ctx, tt.input.UserKey, tt.input.SessionId)
 			if tt.wantEncodeErr {
 				assert.Error(t, err)
 			} else {
@@ -47,14 +60,16 @@ func TestDefaultCodec(t *testing.T) {
 				assert.NotEmpty(t, token)
 			}
 
-			userKey, err := codec.Decrypt(ctx, token)
+			userKey, sessionId, err := codec.Decrypt(ctx, token)
 			if tt.wantDecryptErr {
 				assert.Error(t, err)
+				assert.Empty(t, userKey)
+				assert.Empty(t, sessionId)
 			} else {
 				assert.NoError(t, err)
-				assert.NotEmpty(t, userKey)
+				assert.Equal(t, tt.input.UserKey, userKey)
+				assert.Equal(t, tt.input.SessionId, sessionId)
 			}
-			assert.Equal(t, tt.input.UserKey, userKey)
 		})
 	}
 }
@@ -66,14 +81,14 @@ func BenchmarkEncryptDecryptToken(b *testing.B) {
 	codec := gtoken.NewDefaultCodec("_", []byte("koi29a83idakguqjq29asd9asd8a7jhq"))
 
 	userKey := "123123"
-	token, err := codec.Encode(ctx, userKey)
+	token, err := codec.Encode(ctx, userKey, "s1")
 	if err != nil {
 		b.Error(err)
 	}
 	b.Log(token)
 
 	for i := 0; i < b.N; i++ {
-		decryptUserKey, err := codec.Decrypt(ctx, token)
+		decryptUserKey, _, err := codec.Decrypt(ctx, token)
 		if err != nil {
 			b.Error(err)
 		}
@@ -83,3 +98,216 @@ func BenchmarkEncryptDecryptToken(b *testing.B) {
 		}
 	}
 }
+
+func TestDefaultCodecFormat(t *testing.T) {
+	ctx := gctx.New()
+	encryptKey := []byte("koi29a83idakguqjq29asd9asd8a7jhq")
+	codec := gtoken.NewDefaultCodec("_", encryptKey)
+
+	// userKey包含分隔符
+	token, err := codec.Encode(ctx, "a_b_c", "s1")
+	assert.NoError(t, err)
+	userKey, sessionId, err := codec.Decrypt(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, "a_b_c", userKey)
+	assert.Equal(t, "s1", sessionId)
+
+	// 版本号
+	data, err := gbase64.DecodeString(token)
+	assert.NoError(t, err)
+	assert.Equal(t, gtoken.TokenVersion2, data[0])
+
+	// 篡改任意字节均认证失败
+	for i := range data {
+		tampered := append([]byte{}, data...)
+		tampered[i] ^= 1
+		userKey, _, err = codec.Decrypt(ctx, gbase64.EncodeToString(tampered))
+		assert.Error(t, err)
+		assert.Empty(t, userKey)
+	}
+
+	// 密钥不一致
+	_, _, err = gtoken.NewDefaultCodec("_", []byte("12345678912345678912345678912345")).Decrypt(ctx, token)
+	assert.Error(t, err)
+
+	// v2.0.x旧格式token，随机串作为会话ID
+	randStr, err := gmd5.Encrypt(grand.Letters(10))
+	assert.NoError(t, err)
+	legacyByte, err := gaes.Encrypt([]byte("a_b_"+randStr), encryptKey)
+	assert.NoError(t, err)
+	legacyToken := gbase64.EncodeToString(legacyByte)
+	userKey, sessionId, err = codec.Decrypt(ctx, legacyToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "a_b", userKey)
+	assert.Equal(t, randStr, sessionId)
+
+	// 随机串格式错误
+	invalidByte, err := gaes.Encrypt([]byte("a_b_c"), encryptKey)
+	assert.NoError(t, err)
+	_, _, err = codec.Decrypt(ctx, gbase64.EncodeToString(invalidByte))
+	assert.Error(t, err)
+
+	// 旧格式篡改
+	legacyByte[len(legacyByte)-20] ^= 1
+	_, _, err = codec.Decrypt(ctx, gbase64.EncodeToString(legacyByte))
+	assert.Error(t, err)
+
+	// 拒绝旧格式token
+	codec.DisableLegacy = true
+	_, _, err = codec.Decrypt(ctx, legacyToken)
+	assert.Error(t, err)
+	_, _, err = codec.Decrypt(ctx, token)
+	assert.NoError(t, err)
+
+	// 通过配置项拒绝旧格式token
+	gToken := gtoken.NewDefaultToken(gtoken.Options{EncryptKey: encryptKey, DisableLegacy: true}).(*gtoken.GTokenV2)
+	_, _, err = gToken.Codec.Decrypt(ctx, legacyToken)
+	assert.Error(t, err)
+}
+
+func TestDefaultCodecKeys(t *testing.T) {
+	ctx := gctx.New()
+	oldKey := []byte("koi29a83idakguqjq29asd9asd8a7jhq")
+	newKey := []byte("12345678912345678912345678912345")
+
+	oldCodec := gtoken.NewDefaultCodec("_", oldKey)
+	oldToken, err := oldCodec.Encode(ctx, "alice", "s1")
+	assert.NoError(t, err)
+
+	// 更换密钥，旧密钥仅用于解密
+	codec := gtoken.NewDefaultCodec("_", newKey)
+	codec.Kid = "k2"
+	codec.DecryptKeys = []gtoken.CodecKey{{Kid: "", Key: oldKey}}
+	userKey, sessionId, err := codec.Decrypt(ctx, oldToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", userKey)
+	assert.Equal(t, "s1", sessionId)
+	assert.True(t, codec.NeedReissue(ctx, oldToken))
+
+	newToken, err := codec.Encode(ctx, "alice", "s1")
+	assert.NoError(t, err)
+	assert.False(t, codec.NeedReissue(ctx, newToken))
+	_, _, err = codec.Decrypt(ctx, newToken)
+	assert.NoError(t, err)
+	_, _, err = oldCodec.Decrypt(ctx, newToken)
+	assert.Error(t, err)
+
+	// 旧格式token同样使用旧密钥解密
+	randStr, err := gmd5.Encrypt(grand.Letters(10))
+	assert.NoError(t, err)
+	legacyByte, err := gaes.Encrypt([]byte("alice_"+randStr), oldKey)
+	assert.NoError(t, err)
+	legacyToken := gbase64.EncodeToString(legacyByte)
+	userKey, _, err = codec.Decrypt(ctx, legacyToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", userKey)
+	assert.True(t, codec.NeedReissue(ctx, legacyToken))
+
+	// 移除旧密钥后旧token失效
+	codec.DecryptKeys = nil
+	_, _, err = codec.Decrypt(ctx, oldToken)
+	assert.Error(t, err)
+	_, _, err = codec.Decrypt(ctx, legacyToken)
+	assert.Error(t, err)
+
+	// 密钥ID重复时按密钥ID只能选中一个密钥，初始化时拒绝
+	assert.Panics(t, func() {
+		gtoken.NewDefaultToken(gtoken.Options{DecryptKeys: []gtoken.CodecKey{{Kid: "", Key: oldKey}}})
+	})
+	assert.Panics(t, func() {
+		gtoken.NewDefaultToken(gtoken.Options{EncryptKid: "k2", DecryptKeys: []gtoken.CodecKey{{Kid: "k1", Key: oldKey}, {Kid: "k1", Key: newKey}}})
+	})
+	assert.NotPanics(t, func() {
+		gtoken.NewDefaultToken(gtoken.Options{EncryptKid: "k2", DecryptKeys: []gtoken.CodecKey{{Kid: "", Key: oldKey}, {Kid: "k1", Key: newKey}}})
+	})
+}
+
+func TestDefaultCodecPrefix(t *testing.T) {
+	ctx := gctx.New()
+	encryptKey := []byte("koi29a83idakguqjq29asd9asd8a7jhq")
+	codec := gtoken.NewDefaultCodec("_", encryptKey)
+	plainToken, err := codec.Encode(ctx, "alice", "s1")
+	assert.NoError(t, err)
+
+	codec.Prefix = "gt"
+	token, err := codec.Encode(ctx, "alice", "s1")
+	assert.NoError(t, err)
+	assert.Regexp(t, "^gt_[A-Za-z0-9_-]+_[0-9a-f]{8}$", token)
+	// URL安全，无需编码
+	assert.Equal(t, token, url.QueryEscape(token))
+	userKey, sessionId, err := codec.Decrypt(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", userKey)
+	assert.Equal(t, "s1", sessionId)
+
+	// 校验码错误，不再解密
+	for _, i := range []int{3, len(token) / 2, len(token) - 1} {
+		tampered := []byte(token)
+		if tampered[i] == 'a' {
+			tampered[i] = 'b'
+		} else {
+			tampered[i] = 'a'
+		}
+		_, _, err = codec.Decrypt(ctx, string(tampered))
+		assert.EqualError(t, err, gtoken.MsgErrTokenChecksum)
+	}
+	_, _, err = codec.Decrypt(ctx, "gt_abc")
+	assert.Error(t, err)
+
+	// 配置前缀前签发的token仍可解析
+	userKey, _, err = codec.Decrypt(ctx, plainToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", userKey)
+
+	// 通过配置项启用
+	gToken := gtoken.NewDefaultToken(gtoken.Options{TokenPrefix: "gt"})
+	token, err = gToken.Generate(ctx, "alice", nil)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token, "gt_"))
+	userKey, err = gToken.Validate(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", userKey)
+}
+
+// base64Codec v2.0.x自定义编解码实现
+type base64Codec struct{}
+
+func (c base64Codec) Encode(ctx context.Context, userKey string) (string, error) {
+	return gbase64.EncodeString(userKey), nil
+}
+
+func (c base64Codec) Decrypt(ctx context.Context, token string) (string, error) {
+	return gbase64.DecodeToString(token)
+}
+
+func TestLegacyCodecAdapter(t *testing.T) {
+	ctx := gctx.New()
+	codec := gtoken.NewLegacyCodecAdapter(base64Codec{}, "")
+
+	// userKey可包含分隔符
+	token, err := codec.Encode(ctx, "a_b", "s_1")
+	assert.NoError(t, err)
+	userKey, sessionId, err := codec.Decrypt(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, "a_b", userKey)
+	assert.Equal(t, "s_1", sessionId)
+	_, err = codec.Encode(ctx, "alice", "")
+	assert.Error(t, err)
+
+	// 升级前签发的token不包含sessionId
+	for _, old := range []string{"alice", "a_b", "alice_"} {
+		oldToken, err := base64Codec{}.Encode(ctx, old)
+		assert.NoError(t, err)
+		_, _, err = codec.Decrypt(ctx, oldToken)
+		assert.Error(t, err)
+	}
+
+	// 作为Codec使用
+	gToken := gtoken.NewDefaultToken(gtoken.Options{CachePreKey: "GTokenLegacyCodec:"}).(*gtoken.GTokenV2)
+	gToken.Codec = codec
+	token, err = gToken.Generate(ctx, "alice", nil)
+	assert.NoError(t, err)
+	userKey, err = gToken.Validate(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", userKey)
+}
@@ -1,27 +1,96 @@
 package gtoken
 
+import "github.com/gogf/gf/v2/errors/gcode"
+
 const (
 	CacheModeCache   = 1
 	CacheModeRedis   = 2
 	CacheModeFile    = 3
 	CacheModeFileDat = "gtoken.dat"
 
+	EvictPolicyOldest = 1 // 剔除最早登录会话
+	EvictPolicyLRU    = 2 // 剔除最久未访问会话
+	EvictPolicyReject = 3 // 拒绝新登录
+
 	DefaultTimeout        = 10 * 24 * 60 * 60 * 1000
 	DefaultCacheKey       = "GToken:"
 	DefaultTokenDelimiter = "_"
 	DefaultEncryptKey     = "12345678912345678912345678912345"
+	DefaultTouchInterval  = 60 * 1000
+	DefaultUpdateRetry    = 10
+	DefaultExpiredKeep    = 24 * 60 * 60 * 1000 // 过期会话记录保留时间（毫秒）
+
+	CacheKeyUser    = "user:"    // 用户会话索引缓存key前缀
+	CacheKeySession = "session:" // 会话缓存key前缀
+	CacheKeyRotated = "rotated:" // 已轮换refresh token缓存key前缀
+	CacheKeyKicked  = "kicked:"  // 被剔除会话缓存key前缀
+	CacheKeyExpired = "expired:" // 过期会话缓存key前缀
 
 	KeyUserKey    = "userKey"    // 用户标识
 	KeyCreateTime = "createTime" // 创建时间
 	KeyRefreshNum = "refreshNum" // 刷新次数
 	KeyData       = "data"       // 缓存自定义数据
 	KeyToken      = "token"      // token
+	KeySessionId  = "sessionId"  // 会话ID
+	KeyLoginTime  = "loginTime"  // 登录时间
+	KeyLastSeen   = "lastSeen"   // 最后访问时间
+	KeyDevice     = "device"     // 设备信息
+
+	KeyRefreshToken = "refreshToken" // 刷新token
+	KeyRefreshTime  = "refreshTime"  // 刷新token创建时间
+	KeyExpireTime   = "expireTime"   // 过期时间
+	KeyValues       = "values"       // 会话键值
+	KeyVersion      = "version"      // 会话键值版本号
+	KeyPrevToken    = "prevToken"    // 重新签发前的token
+	KeyExpireCode   = "expireCode"   // 过期原因错误码
+
+	KeyTokenHash        = "tokenHash"        // token摘要
+	KeyRefreshTokenHash = "refreshTokenHash" // 刷新token摘要
+	KeyPrevTokenHash    = "prevTokenHash"    // 重新签发前的token摘要
+
+	KeyDeviceIp        = "ip"        // 设备IP
+	KeyDeviceUserAgent = "userAgent" // 设备UserAgent
+
+	// HeaderRenewToken 重新签发token响应头，客户端收到后替换本地token
+	HeaderRenewToken = "X-Renew-Token"
 )
 
 const (
-	MsgErrUserKeyEmpty = "userKey empty"
-	MsgErrTokenEmpty   = "token is empty"
-	MsgErrTokenLen     = "token len error"
-	MsgErrValidate     = "user validate error"
-	MsgErrDataEmpty    = "cache value is nil"
+	MsgErrUserKeyEmpty     = "userKey empty"
+	MsgErrSessionIdEmpty   = "sessionId empty"
+	MsgErrTokenEmpty       = "token is empty"
+	MsgErrTokenLen         = "token len error"
+	MsgErrTokenVersion     = "token version error"
+	MsgErrTokenAuth        = "token authentication failed"
+	MsgErrTokenKey         = "token key not found"
+	MsgErrTokenKid         = "duplicate token key id"
+	MsgErrTokenChecksum    = "token checksum error"
+	MsgErrCacheDecrypt     = "cache data decrypt error"
+	MsgErrCacheEncryptKey  = "cache encrypt key must differ from token encrypt key"
+	MsgErrValidate         = "user validate error"
+	MsgErrDataEmpty        = "cache value is nil"
+	MsgErrNotSupport       = "method not support"
+	MsgErrTokenExpired     = "token expired"
+	MsgErrRefreshDisabled  = "refresh token not enabled"
+	MsgErrRefreshTimes     = "refresh times exceeded"
+	MsgErrRefreshReused    = "refresh token reused"
+	MsgErrIdleTimeout      = "session idle timeout"
+	MsgErrLifetimeExceeded = "session lifetime exceeded"
+	MsgErrKickedOut        = "user logged in elsewhere"
+	MsgErrSessionLimit     = "session limit exceeded"
+	MsgErrUpdateConflict   = "cache update conflict"
+	MsgErrVersionConflict  = "session values version conflict"
+	MsgErrTokenRevoked     = "token revoked"
+	MsgErrTokenNotStored   = "token not stored, only token hash is cached"
+)
+
+var (
+	CodeTokenExpired     = gcode.New(1001, "Token Expired", nil)     // token超时
+	CodeIdleTimeout      = gcode.New(1002, "Idle Timeout", nil)      // 会话空闲超时
+	CodeLifetimeExceeded = gcode.New(1003, "Lifetime Exceeded", nil) // 会话超过最长有效期
+	CodeKickedOut        = gcode.New(1004, "Kicked Out", nil)        // 会话被其他设备登录剔除
+	CodeSessionLimit     = gcode.New(1005, "Session Limit", nil)     // 会话数超过限制
+	CodeVersionConflict  = gcode.New(1006, "Version Conflict", nil)  // 会话键值版本冲突
+	CodeTokenRevoked     = gcode.New(1007, "Token Revoked", nil)     // token已注销
+	CodeTokenNotStored   = gcode.New(1008, "Token Not Stored", nil)  // 缓存未保存token原文
 )
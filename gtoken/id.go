@@ -0,0 +1,94 @@
+package gtoken
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"github.com/google/uuid"
+	"io"
+	"time"
+)
+
+const (
+	IdModeRandom = 1 // crypto/rand随机ID
+	IdModeUUIDv7 = 2 // UUIDv7，按时间有序
+	IdModeULID   = 3 // ULID，按时间有序
+
+	DefaultIdSize = 16 // 随机ID默认字节数
+	MinIdSize     = 8  // 随机ID最小字节数
+
+	crockfordBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+)
+
+// IdGenerator 会话ID生成接口
+type IdGenerator interface {
+	NewId(ctx context.Context) (id string, err error)
+}
+
+// NewIdGenerator 根据生成方式创建ID生成器，随机ID字节数小于MinIdSize时使用DefaultIdSize
+func NewIdGenerator(mode int8, size int) IdGenerator {
+	switch mode {
+	case IdModeUUIDv7:
+		return UUIDv7IdGenerator{}
+	case IdModeULID:
+		return ULIDIdGenerator{}
+	default:
+		if size < MinIdSize {
+			size = DefaultIdSize
+		}
+		return RandomIdGenerator{Size: size}
+	}
+}
+
+// RandomIdGenerator crypto/rand随机ID，URL安全base64编码
+type RandomIdGenerator struct {
+	Size int // 字节数
+}
+
+func (g RandomIdGenerator) NewId(ctx context.Context) (string, error) {
+	size := g.Size
+	if size <= 0 {
+		size = DefaultIdSize
+	}
+	b := make([]byte, size)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// UUIDv7IdGenerator UUIDv7，前48位为毫秒时间戳
+type UUIDv7IdGenerator struct{}
+
+func (g UUIDv7IdGenerator) NewId(ctx context.Context) (string, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// ULIDIdGenerator ULID，48位毫秒时间戳 + 80位随机数，Crockford base32编码
+type ULIDIdGenerator struct{}
+
+func (g ULIDIdGenerator) NewId(ctx context.Context) (string, error) {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], uint64(time.Now().UnixMilli())<<16)
+	if _, err := io.ReadFull(rand.Reader, b[6:]); err != nil {
+		return "", err
+	}
+	return encodeULID(b), nil
+}
+
+// encodeULID 128位按5位一组编码为26位字符，首字符仅使用3位
+func encodeULID(b [16]byte) string {
+	hi, lo := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	out := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		out[i] = crockfordBase32[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out)
+}
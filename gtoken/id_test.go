@@ -0,0 +1,88 @@
+package gtoken_test
+
+import (
+	"context"
+	"encoding/base64"
+	"github.com/goflyfox/gtoken/v2/gtoken"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"testing"
+	"time"
+)
+
+type counterIdGenerator struct {
+	n int
+}
+
+func (g *counterIdGenerator) NewId(ctx context.Context) (string, error) {
+	g.n++
+	return "sid" + string(rune('0'+g.n)), nil
+}
+
+func TestIdGenerator(t *testing.T) {
+	ctx := gctx.New()
+
+	// 随机ID，字节数可配置，过短时使用默认值
+	for size, want := range map[int]int{0: gtoken.DefaultIdSize, 4: gtoken.DefaultIdSize, 32: 32} {
+		id, err := gtoken.NewIdGenerator(gtoken.IdModeRandom, size).NewId(ctx)
+		assert.NoError(t, err)
+		b, err := base64.RawURLEncoding.DecodeString(id)
+		assert.NoError(t, err)
+		assert.Len(t, b, want)
+	}
+	ids := map[string]bool{}
+	generator := gtoken.NewIdGenerator(gtoken.IdModeRandom, 0)
+	for i := 0; i < 1000; i++ {
+		id, err := generator.NewId(ctx)
+		assert.NoError(t, err)
+		assert.False(t, ids[id])
+		ids[id] = true
+	}
+
+	// UUIDv7
+	id, err := gtoken.NewIdGenerator(gtoken.IdModeUUIDv7, 0).NewId(ctx)
+	assert.NoError(t, err)
+	parsed, err := uuid.Parse(id)
+	assert.NoError(t, err)
+	assert.Equal(t, uuid.Version(7), parsed.Version())
+
+	// ULID，按时间有序
+	generator = gtoken.NewIdGenerator(gtoken.IdModeULID, 0)
+	var ulids []string
+	for i := 0; i < 3; i++ {
+		id, err = generator.NewId(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, id, 26)
+		assert.Regexp(t, "^[0-7][0-9A-HJKMNP-TV-Z]{25}$", id)
+		ulids = append(ulids, id)
+		time.Sleep(2 * time.Millisecond)
+	}
+	assert.True(t, sort.StringsAreSorted(ulids))
+}
+
+func TestSessionId(t *testing.T) {
+	ctx := gctx.New()
+	userKey := "testUser"
+
+	// 配置生成方式
+	gToken := gtoken.NewDefaultToken(gtoken.Options{IdMode: gtoken.IdModeULID})
+	_, err := gToken.Generate(ctx, userKey, nil)
+	assert.NoError(t, err)
+	sessions, err := gToken.Sessions(ctx, userKey)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+	assert.Len(t, sessions[0].SessionId, 26)
+
+	// 自定义生成器
+	gToken = gtoken.NewDefaultToken(gtoken.Options{})
+	gToken.(*gtoken.GTokenV2).IdGenerator = &counterIdGenerator{}
+	token, err := gToken.Generate(ctx, userKey, nil)
+	assert.NoError(t, err)
+	sessions, err = gToken.Sessions(ctx, userKey)
+	assert.NoError(t, err)
+	assert.Equal(t, "sid1", sessions[0].SessionId)
+	_, err = gToken.Validate(ctx, token)
+	assert.NoError(t, err)
+}
@@ -96,6 +96,11 @@ func (m Middleware) HasExcludePath(r *ghttp.Request) bool {
 	return false
 }
 
+// IsKickedOut 判断认证失败是否由于账号在其他设备登录，可在ResFun中区分提示
+func IsKickedOut(err error) bool {
+	return gerror.Code(err).Code() == CodeKickedOut.Code()
+}
+
 // GetUserKey 返回请求
 func GetUserKey(ctx context.Context) string {
 	return g.RequestFromCtx(ctx).GetCtxVar(KeyUserKey).String()
@@ -0,0 +1,43 @@
+package gtoken
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+// Session 用户登录会话
+type Session struct {
+	SessionId  string // 会话ID
+	UserKey    string // 用户标识
+	LoginTime  int64  // 登录时间（毫秒）
+	CreateTime int64  // 创建时间，刷新后重置（毫秒）
+	RefreshNum int    // 刷新次数
+	LastSeen   int64  // 最后访问时间（毫秒）
+	Device     g.Map  // 设备信息
+}
+
+// newSession 通过会话缓存构建Session
+func newSession(userCache g.Map) Session {
+	return Session{
+		SessionId:  gconv.String(userCache[KeySessionId]),
+		UserKey:    gconv.String(userCache[KeyUserKey]),
+		LoginTime:  gconv.Int64(userCache[KeyLoginTime]),
+		CreateTime: gconv.Int64(userCache[KeyCreateTime]),
+		RefreshNum: gconv.Int(userCache[KeyRefreshNum]),
+		LastSeen:   gconv.Int64(userCache[KeyLastSeen]),
+		Device:     gconv.Map(userCache[KeyDevice]),
+	}
+}
+
+// getDevice 获取登录设备信息，非http请求上下文时返回nil
+func getDevice(ctx context.Context) g.Map {
+	r := g.RequestFromCtx(ctx)
+	if r == nil {
+		return nil
+	}
+	return g.Map{
+		KeyDeviceIp:        r.GetClientIp(),
+		KeyDeviceUserAgent: r.UserAgent(),
+	}
+}
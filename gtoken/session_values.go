@@ -0,0 +1,102 @@
+package gtoken
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/container/gvar"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+// Values 获取token所属会话全部键值及版本号
+func (m *GTokenV2) Values(ctx context.Context, token string) (values g.Map, version int64, err error) {
+	if token == "" {
+		err = gerror.NewCode(gcode.CodeMissingParameter, MsgErrTokenEmpty)
+		return
+	}
+
+	userCache, err := m.loadSession(ctx, token, KeyToken)
+	if err != nil {
+		return
+	}
+	values = gconv.Map(userCache[KeyValues])
+	if values == nil {
+		values = g.Map{}
+	}
+	return values, gconv.Int64(userCache[KeyVersion]), nil
+}
+
+// GetValue 获取token所属会话键值
+func (m *GTokenV2) GetValue(ctx context.Context, token, key string) (value *gvar.Var, err error) {
+	values, _, err := m.Values(ctx, token)
+	if err != nil {
+		return
+	}
+	return gvar.New(values[key]), nil
+}
+
+// SetValue 设置token所属会话键值，仅修改指定key，并发请求不会覆盖其他key
+func (m *GTokenV2) SetValue(ctx context.Context, token, key string, value any) (version int64, err error) {
+	return m.updateValues(ctx, token, -1, func(values g.Map) {
+		values[key] = value
+	})
+}
+
+// SetValueIfVersion 版本号一致时设置token所属会话键值，否则返回CodeVersionConflict
+// 用于读取Values后基于读取结果修改的场景，避免覆盖其他请求的修改
+func (m *GTokenV2) SetValueIfVersion(ctx context.Context, token, key string, value any, version int64) (newVersion int64, err error) {
+	if version < 0 {
+		err = gerror.NewCode(gcode.CodeInvalidParameter, MsgErrVersionConflict)
+		return
+	}
+	return m.updateValues(ctx, token, version, func(values g.Map) {
+		values[key] = value
+	})
+}
+
+// DeleteValue 删除token所属会话键值
+func (m *GTokenV2) DeleteValue(ctx context.Context, token, key string) (version int64, err error) {
+	return m.updateValues(ctx, token, -1, func(values g.Map) {
+		delete(values, key)
+	})
+}
+
+// updateValues 原子更新会话键值并递增版本号；version小于0时不校验版本号
+func (m *GTokenV2) updateValues(ctx context.Context, token string, version int64, f func(values g.Map)) (newVersion int64, err error) {
+	if token == "" {
+		err = gerror.NewCode(gcode.CodeMissingParameter, MsgErrTokenEmpty)
+		return
+	}
+
+	userCache, err := m.loadSession(ctx, token, KeyToken)
+	if err != nil {
+		return
+	}
+	userCache, err = m.updateSession(ctx, gconv.String(userCache[KeyUserKey]), gconv.String(userCache[KeySessionId]), func(userCache g.Map) error {
+		currentVersion := gconv.Int64(userCache[KeyVersion])
+		if version >= 0 && version != currentVersion {
+			return gerror.NewCode(CodeVersionConflict, MsgErrVersionConflict)
+		}
+		values := gconv.Map(userCache[KeyValues])
+		if values == nil {
+			values = g.Map{}
+		}
+		f(values)
+		newVersion = currentVersion + 1
+		userCache[KeyValues] = values
+		userCache[KeyVersion] = newVersion
+		return nil
+	})
+	if err != nil {
+		if gerror.Code(err) == gcode.CodeNil {
+			err = gerror.WrapCode(gcode.CodeInternalError, err)
+		}
+		return
+	}
+	if userCache == nil {
+		err = gerror.NewCode(gcode.CodeInternalError, MsgErrDataEmpty)
+		return
+	}
+	return
+}
@@ -0,0 +1,85 @@
+package gtoken_test
+
+import (
+	"fmt"
+	"github.com/goflyfox/gtoken/v2/gtoken"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/util/gconv"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+func TestSessionValues(t *testing.T) {
+	var (
+		ctx     = gctx.New()
+		userKey = "testUser"
+	)
+	for _, cacheMode := range []int8{gtoken.CacheModeCache, gtoken.CacheModeFile} {
+		gToken := gtoken.NewDefaultToken(gtoken.Options{
+			CacheMode:   cacheMode,
+			CachePreKey: "GTokenValues:",
+			MultiLogin:  true,
+		})
+		token, err := gToken.Generate(ctx, userKey, g.Map{"role": "user"})
+		assert.NoError(t, err)
+		other, err := gToken.Generate(ctx, userKey, g.Map{"role": "user"})
+		assert.NoError(t, err)
+
+		values, version, err := gToken.Values(ctx, token)
+		assert.NoError(t, err)
+		assert.Empty(t, values)
+		assert.Equal(t, int64(0), version)
+
+		version, err = gToken.SetValue(ctx, token, "tenant", "t1")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), version)
+		value, err := gToken.GetValue(ctx, token, "tenant")
+		assert.NoError(t, err)
+		assert.Equal(t, "t1", value.String())
+		// 会话键值互不影响，登录数据不变
+		value, err = gToken.GetValue(ctx, other, "tenant")
+		assert.NoError(t, err)
+		assert.True(t, value.IsNil())
+		_, data, err := gToken.ParseToken(ctx, token)
+		assert.NoError(t, err)
+		assert.Equal(t, g.Map{"role": "user"}, data)
+
+		// 版本号不一致时拒绝修改
+		_, err = gToken.SetValueIfVersion(ctx, token, "step", 2, 0)
+		assert.Equal(t, gtoken.CodeVersionConflict, gerror.Code(err))
+		version, err = gToken.SetValueIfVersion(ctx, token, "step", 2, version)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), version)
+
+		version, err = gToken.DeleteValue(ctx, token, "tenant")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), version)
+		values, _, err = gToken.Values(ctx, token)
+		assert.NoError(t, err)
+		assert.Len(t, values, 1)
+		assert.Equal(t, 2, gconv.Int(values["step"]))
+
+		// 并发设置不同key不互相覆盖
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, err := gToken.SetValue(ctx, token, fmt.Sprintf("key%d", i), i)
+				assert.NoError(t, err)
+			}(i)
+		}
+		wg.Wait()
+		values, version, err = gToken.Values(ctx, token)
+		assert.NoError(t, err)
+		assert.Len(t, values, 21)
+		assert.Equal(t, int64(23), version)
+
+		assert.NoError(t, gToken.Destroy(ctx, userKey))
+		_, err = gToken.SetValue(ctx, token, "tenant", "t2")
+		assert.Error(t, err)
+	}
+}
@@ -2,12 +2,14 @@ package gtoken
 
 import (
 	"context"
+	"github.com/gogf/gf/v2/container/gvar"
 	"github.com/gogf/gf/v2/errors/gcode"
 	"github.com/gogf/gf/v2/errors/gerror"
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/gogf/gf/v2/os/gctx"
 	"github.com/gogf/gf/v2/os/gtime"
 	"github.com/gogf/gf/v2/util/gconv"
+	"sort"
 )
 
 // Token 接口
@@ -20,17 +22,50 @@ type Token interface {
 	Get(ctx context.Context, userKey string) (token string, data any, err error)
 	// ParseToken 通过token获取userKey,data
 	ParseToken(ctx context.Context, token string) (userKey string, data any, err error)
-	// Destroy 销毁 Token
+	// Destroy 销毁用户全部 Token
 	Destroy(ctx context.Context, userKey string) error
+	// Sessions 获取用户全部有效会话
+	Sessions(ctx context.Context, userKey string) (sessions []Session, err error)
+	// DestroySession 通过userKey,sessionId销毁单个会话
+	DestroySession(ctx context.Context, userKey, sessionId string) error
+	// DestroyToken 通过token销毁单个会话
+	DestroyToken(ctx context.Context, token string) error
+	// UpdateData 更新用户全部会话数据，不重新生成token
+	UpdateData(ctx context.Context, userKey string, data any) error
+	// UpdateSessionData 更新单个会话数据，不重新生成token
+	UpdateSessionData(ctx context.Context, userKey, sessionId string, data any) error
+	// Values 获取token所属会话全部键值及版本号
+	Values(ctx context.Context, token string) (values g.Map, version int64, err error)
+	// GetValue 获取token所属会话键值
+	GetValue(ctx context.Context, token, key string) (value *gvar.Var, err error)
+	// SetValue 设置token所属会话键值
+	SetValue(ctx context.Context, token, key string, value any) (version int64, err error)
+	// SetValueIfVersion 版本号一致时设置token所属会话键值
+	SetValueIfVersion(ctx context.Context, token, key string, value any, version int64) (newVersion int64, err error)
+	// DeleteValue 删除token所属会话键值
+	DeleteValue(ctx context.Context, token, key string) (version int64, err error)
+	// GeneratePair 生成 access token + refresh token
+	GeneratePair(ctx context.Context, userKey string, data any) (pair TokenPair, err error)
+	// Refresh 通过 refresh token 换取新的 TokenPair
+	Refresh(ctx context.Context, refreshToken string) (pair TokenPair, err error)
 	// GetOptions 获取配置参数
 	GetOptions() Options
 }
 
+// TokenPair 双token
+type TokenPair struct {
+	AccessToken   string // 访问token
+	RefreshToken  string // 刷新token
+	AccessExpire  int64  // 访问token过期时间（毫秒）
+	RefreshExpire int64  // 刷新token过期时间（毫秒）
+}
+
 // GTokenV2 gtoken结构体
 type GTokenV2 struct {
-	Options Options
-	Codec   Codec
-	Cache   Cache
+	Options     Options
+	Codec       Codec
+	Cache       Cache
+	IdGenerator IdGenerator
 }
 
 func NewDefaultTokenByConfig() Token {
@@ -63,120 +98,243 @@ func NewDefaultToken(options Options) Token {
 	if options.TokenDelimiter == "" {
 		options.TokenDelimiter = DefaultTokenDelimiter
 	}
+	if options.EvictPolicy == 0 {
+		options.EvictPolicy = EvictPolicyOldest
+	}
+	if options.IdMode == 0 {
+		options.IdMode = IdModeRandom
+	}
+
+	// 双token模式下，会话需保留至refresh token过期
+	cacheTimeout := options.Timeout
+	if options.RefreshTimeout > cacheTimeout {
+		cacheTimeout = options.RefreshTimeout
+	}
+
+	codec := NewDefaultCodec(options.TokenDelimiter, options.EncryptKey)
+	codec.Kid = options.EncryptKid
+	codec.DecryptKeys = options.DecryptKeys
+	codec.Prefix = options.TokenPrefix
+	codec.DisableLegacy = options.DisableLegacy
+	if err := codec.checkKids(); err != nil {
+		panic(err)
+	}
+
+	cache := NewDefaultCache(options.CacheMode, options.CachePreKey, cacheTimeout)
+	if len(options.CacheEncryptKey) > 0 {
+		// 缓存数据加密key不能与token加解密及摘要密钥相同
+		encryptKeys := [][]byte{options.EncryptKey, options.TokenHashKey}
+		for _, key := range options.DecryptKeys {
+			encryptKeys = append(encryptKeys, key.Key)
+		}
+		if err := CheckCacheEncryptKey(options.CacheEncryptKey, encryptKeys...); err != nil {
+			panic(err)
+		}
+		cache.DataKey = options.CacheEncryptKey
+	}
 
 	gfToken := &GTokenV2{
-		Options: options,
-		Codec:   NewDefaultCodec(options.TokenDelimiter, options.EncryptKey),
-		Cache:   NewDefaultCache(options.CacheMode, options.CachePreKey, options.Timeout),
+		Options:     options,
+		Codec:       codec,
+		Cache:       cache,
+		IdGenerator: NewIdGenerator(options.IdMode, options.IdSize),
 	}
 	g.Log().Debug(gctx.New(), "token options", options.String())
 	return gfToken
 }
 
 // Generate 生成 Token
+// 每次调用都会创建独立会话；非多端登录时，会先销毁该用户已有会话
 func (m *GTokenV2) Generate(ctx context.Context, userKey string, data any) (token string, err error) {
-	if userKey == "" {
-		err = gerror.NewCode(gcode.CodeMissingParameter, MsgErrUserKeyEmpty)
+	userCache, token, err := m.createSession(ctx, userKey, data)
+	if err != nil {
 		return
 	}
 
-	if m.Options.MultiLogin {
-		// 支持多端重复登录，如果获取到返回相同token
-		token, _, err = m.Get(ctx, userKey)
-		if err == nil && token != "" {
-			return
+	err = m.saveSession(ctx, userCache)
+	if err != nil {
+		if gerror.Code(err) == gcode.CodeNil {
+			err = gerror.WrapCode(gcode.CodeInternalError, err)
 		}
+		return
 	}
 
-	token, err = m.Codec.Encode(ctx, userKey)
-	if err != nil {
-		err = gerror.WrapCode(gcode.CodeInternalError, err)
+	return token, nil
+}
+
+// GeneratePair 生成 access token + refresh token
+// 需配置RefreshTimeout；access token过期后通过Refresh换取新的TokenPair，不再自动续期
+func (m *GTokenV2) GeneratePair(ctx context.Context, userKey string, data any) (pair TokenPair, err error) {
+	if m.Options.RefreshTimeout <= 0 {
+		err = gerror.NewCode(gcode.CodeInvalidConfiguration, MsgErrRefreshDisabled)
 		return
 	}
 
-	userCache := g.Map{
-		KeyUserKey:    userKey,
-		KeyToken:      token,
-		KeyData:       data,
-		KeyRefreshNum: 0,
-		KeyCreateTime: gtime.Now().TimestampMilli(),
+	userCache, _, err := m.createSession(ctx, userKey, data)
+	if err != nil {
+		return
 	}
-
-	err = m.Cache.Set(ctx, userKey, userCache)
+	pair, err = m.issuePair(ctx, userCache)
 	if err != nil {
-		err = gerror.WrapCode(gcode.CodeInternalError, err)
 		return
 	}
 
+	err = m.saveSession(ctx, userCache)
+	if err != nil {
+		if gerror.Code(err) == gcode.CodeNil {
+			err = gerror.WrapCode(gcode.CodeInternalError, err)
+		}
+		return
+	}
 	return
 }
 
-// Validate 验证 Token
-func (m *GTokenV2) Validate(ctx context.Context, token string) (userKey string, err error) {
-	if token == "" {
+// Refresh 通过 refresh token 换取新的 TokenPair，原 access token 及 refresh token 失效
+func (m *GTokenV2) Refresh(ctx context.Context, refreshToken string) (pair TokenPair, err error) {
+	if m.Options.RefreshTimeout <= 0 {
+		err = gerror.NewCode(gcode.CodeInvalidConfiguration, MsgErrRefreshDisabled)
+		return
+	}
+	if refreshToken == "" {
 		err = gerror.NewCode(gcode.CodeMissingParameter, MsgErrTokenEmpty)
 		return
 	}
 
-	userKey, err = m.Codec.Decrypt(ctx, token)
+	userCache, err := m.loadSession(ctx, refreshToken, KeyRefreshToken)
 	if err != nil {
-		err = gerror.WrapCode(gcode.CodeInvalidParameter, err)
+		// 已轮换的refresh token再次使用，视为token泄露，销毁整个会话
+		reused, e := m.revokeReused(ctx, refreshToken)
+		if e != nil {
+			err = gerror.WrapCode(gcode.CodeInternalError, e)
+		} else if reused {
+			err = gerror.NewCode(gcode.CodeSecurityReason, MsgErrRefreshReused)
+		}
+		return
+	}
+	if m.Options.MaxRefreshTimes > 0 && gconv.Int(userCache[KeyRefreshNum]) >= m.Options.MaxRefreshTimes {
+		err = gerror.NewCode(gcode.CodeInvalidOperation, MsgErrRefreshTimes)
 		return
 	}
-	userCache, err := m.Cache.Get(ctx, userKey)
+
+	userCache, err = m.updateSession(ctx, gconv.String(userCache[KeyUserKey]), gconv.String(userCache[KeySessionId]), func(userCache g.Map) error {
+		// 并发刷新时，仅第一个请求可以完成轮换
+		if !m.matchToken(userCache, KeyRefreshToken, refreshToken) {
+			return gerror.NewCode(gcode.CodeInvalidParameter, MsgErrValidate)
+		}
+		userCache[KeyRefreshNum] = gconv.Int(userCache[KeyRefreshNum]) + 1
+		userCache[KeyLastSeen] = gtime.Now().TimestampMilli()
+		pair, err = m.issuePair(ctx, userCache)
+		return err
+	})
 	if err != nil {
+		if gerror.Code(err) == gcode.CodeNil {
+			err = gerror.WrapCode(gcode.CodeInternalError, err)
+		}
 		return
 	}
 	if userCache == nil {
 		err = gerror.NewCode(gcode.CodeInternalError, MsgErrDataEmpty)
 		return
 	}
-	if token != userCache[KeyToken] {
-		err = gerror.NewCode(gcode.CodeInvalidParameter, MsgErrValidate)
+	// 记录已轮换的refresh token，用于重用检测
+	err = m.Cache.Set(ctx, rotatedCacheKey(m.hashToken(refreshToken)), g.Map{
+		KeyUserKey:    userCache[KeyUserKey],
+		KeySessionId:  userCache[KeySessionId],
+		KeyCreateTime: gtime.Now().TimestampMilli(),
+	})
+	if err != nil {
+		err = gerror.WrapCode(gcode.CodeInternalError, err)
 		return
 	}
+	return
+}
 
-	// 需要进行缓存超时时间刷新
-	refreshToken := func() {
-		nowTime := gtime.Now().TimestampMilli()
-		createTime := userCache[KeyCreateTime]
-		refreshNum := gconv.Int(userCache[KeyRefreshNum])
-		if m.Options.MaxRefresh == 0 {
-			return
+// Validate 验证 Token
+func (m *GTokenV2) Validate(ctx context.Context, token string) (userKey string, err error) {
+	if token == "" {
+		err = gerror.NewCode(gcode.CodeMissingParameter, MsgErrTokenEmpty)
+		return
+	}
+
+	userCache, err := m.loadSession(ctx, token, KeyToken)
+	if err != nil {
+		return
+	}
+	userKey = gconv.String(userCache[KeyUserKey])
+
+	var (
+		nowTime = gtime.Now().TimestampMilli()
+		// 更新最后访问时间，按间隔写入避免每次请求写缓存
+		touch = nowTime-gconv.Int64(userCache[KeyLastSeen]) >= m.touchInterval()
+		// 需要进行缓存超时时间刷新
+		refresh = m.needRefresh(userCache, nowTime)
+		// 续期时使用当前密钥重新签发token
+		reissue   = refresh && m.needReissue(ctx, token)
+		sessionId = gconv.String(userCache[KeySessionId])
+		newToken  string
+	)
+	if !touch && !refresh {
+		return
+	}
+
+	_, err = m.updateSession(ctx, userKey, sessionId, func(userCache g.Map) error {
+		if touch {
+			userCache[KeyLastSeen] = nowTime
 		}
-		if m.Options.MaxRefreshTimes > 0 && refreshNum >= m.Options.MaxRefreshTimes {
-			return
+		if refresh {
+			userCache[KeyRefreshNum] = gconv.Int(userCache[KeyRefreshNum]) + 1
+			userCache[KeyCreateTime] = nowTime
+			// 客户端已使用重新签发的token时，删除重新签发前的token；否则保留，避免客户端未收到新token时失效
+			if m.matchToken(userCache, KeyToken, token) {
+				m.removeToken(userCache, KeyPrevToken)
+			}
 		}
-		if nowTime > gconv.Int64(createTime)+m.Options.MaxRefresh {
-			userCache[KeyRefreshNum] = refreshNum + 1
-			userCache[KeyCreateTime] = gtime.Now().TimestampMilli()
-			err = m.Cache.Set(ctx, userKey, userCache)
-			if err != nil {
-				err = gerror.WrapCode(gcode.CodeInternalError, err)
-				return
+		// 并发请求时，仅第一个请求重新签发
+		if reissue && m.matchToken(userCache, KeyToken, token) {
+			var e error
+			if newToken, e = m.Codec.Encode(ctx, userKey, sessionId); e != nil {
+				return e
 			}
+			m.setToken(userCache, KeyPrevToken, token)
+			m.setToken(userCache, KeyToken, newToken)
+		}
+		return nil
+	})
+	if err != nil {
+		err = gerror.WrapCode(gcode.CodeInternalError, err)
+		return
+	}
+	if newToken != "" {
+		if r := g.RequestFromCtx(ctx); r != nil {
+			r.Response.Header().Set(HeaderRenewToken, newToken)
 		}
 	}
-	refreshToken()
 
 	return
 }
 
 // Get 通过userKey获取Token
+// 多端登录时返回最近一次登录会话的token,data；缓存仅保存token摘要，未开启StoreRawToken时返回CodeTokenNotStored错误及data
 func (m *GTokenV2) Get(ctx context.Context, userKey string) (token string, data any, err error) {
 	if userKey == "" {
 		err = gerror.NewCode(gcode.CodeMissingParameter, MsgErrUserKeyEmpty)
 		return
 	}
 
-	userCache, err := m.Cache.Get(ctx, userKey)
+	sessions, err := m.getSessions(ctx, userKey)
 	if err != nil {
 		return "", nil, gerror.WrapCode(gcode.CodeInternalError, err)
 	}
-	if userCache == nil {
+	if len(sessions) == 0 {
 		return "", nil, gerror.NewCode(gcode.CodeInternalError, MsgErrDataEmpty)
 	}
-	return gconv.String(userCache[KeyToken]), userCache[KeyData], nil
+	userCache := sessions[len(sessions)-1]
+	token = gconv.String(userCache[KeyToken])
+	if token == "" {
+		// 缓存仅保存token摘要，仍返回会话数据
+		return "", userCache[KeyData], gerror.NewCode(CodeTokenNotStored, MsgErrTokenNotStored)
+	}
+	return token, userCache[KeyData], nil
 }
 
 // ParseToken 通过token获取userKey,data
@@ -186,32 +344,140 @@ func (m *GTokenV2) ParseToken(ctx context.Context, token string) (userKey string
 		return
 	}
 
-	userKey, err = m.Codec.Decrypt(ctx, token)
+	userCache, err := m.loadSession(ctx, token, KeyToken)
 	if err != nil {
-		err = gerror.WrapCode(gcode.CodeInvalidParameter, err)
+		return
+	}
+	return gconv.String(userCache[KeyUserKey]), userCache[KeyData], nil
+}
+
+// Destroy 通过userKey销毁该用户全部会话Token
+func (m *GTokenV2) Destroy(ctx context.Context, userKey string) error {
+	if userKey == "" {
+		return gerror.NewCode(gcode.CodeMissingParameter, MsgErrUserKeyEmpty)
+	}
+
+	sessionIds, err := m.getSessionIds(ctx, userKey)
+	if err != nil {
+		return gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	for sessionId := range sessionIds {
+		err = m.Cache.Remove(ctx, sessionCacheKey(userKey, sessionId))
+		if err != nil {
+			return gerror.WrapCode(gcode.CodeInternalError, err)
+		}
+	}
+	err = m.Cache.Remove(ctx, userCacheKey(userKey))
+	if err != nil {
+		return gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	// 升级前登录的会话
+	err = m.removeLegacy(ctx, userKey)
+	if err != nil {
+		return gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	return nil
+}
+
+// Sessions 获取用户全部有效会话，按登录时间升序
+func (m *GTokenV2) Sessions(ctx context.Context, userKey string) (sessions []Session, err error) {
+	if userKey == "" {
+		err = gerror.NewCode(gcode.CodeMissingParameter, MsgErrUserKeyEmpty)
 		return
 	}
 
-	userCache, err := m.Cache.Get(ctx, userKey)
+	userCaches, err := m.getSessions(ctx, userKey)
 	if err != nil {
-		return "", nil, gerror.WrapCode(gcode.CodeInternalError, err)
+		return nil, gerror.WrapCode(gcode.CodeInternalError, err)
 	}
-	if userCache == nil {
-		return "", nil, gerror.NewCode(gcode.CodeInternalError, MsgErrDataEmpty)
+	sessions = make([]Session, 0, len(userCaches))
+	for _, userCache := range userCaches {
+		sessions = append(sessions, newSession(userCache))
 	}
-	return userKey, userCache[KeyData], nil
+	return sessions, nil
 }
 
-// Destroy 通过userKey销毁Token
-func (m *GTokenV2) Destroy(ctx context.Context, userKey string) error {
+// DestroySession 通过userKey,sessionId销毁单个会话
+func (m *GTokenV2) DestroySession(ctx context.Context, userKey, sessionId string) error {
+	if userKey == "" {
+		return gerror.NewCode(gcode.CodeMissingParameter, MsgErrUserKeyEmpty)
+	}
+	if sessionId == "" {
+		return gerror.NewCode(gcode.CodeMissingParameter, MsgErrSessionIdEmpty)
+	}
+
+	err := m.removeSession(ctx, userKey, sessionId)
+	if err != nil {
+		return gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	return nil
+}
+
+// DestroyToken 通过token销毁单个会话
+func (m *GTokenV2) DestroyToken(ctx context.Context, token string) error {
+	if token == "" {
+		return gerror.NewCode(gcode.CodeMissingParameter, MsgErrTokenEmpty)
+	}
+
+	userKey, sessionId, err := m.Codec.Decrypt(ctx, token)
+	if err != nil {
+		return gerror.WrapCode(gcode.CodeInvalidParameter, err)
+	}
+	// 升级前登录且未迁移的会话
+	legacy, err := m.getLegacy(ctx, userKey, token)
+	if err == nil && legacy != nil {
+		err = m.Cache.Remove(ctx, userKey)
+	}
+	if err != nil {
+		return gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	return m.DestroySession(ctx, userKey, sessionId)
+}
+
+// UpdateData 更新用户全部会话数据，不重新生成token
+func (m *GTokenV2) UpdateData(ctx context.Context, userKey string, data any) error {
 	if userKey == "" {
 		return gerror.NewCode(gcode.CodeMissingParameter, MsgErrUserKeyEmpty)
 	}
 
-	err := m.Cache.Remove(ctx, userKey)
+	sessionIds, err := m.getSessionIds(ctx, userKey)
 	if err != nil {
 		return gerror.WrapCode(gcode.CodeInternalError, err)
 	}
+	if len(sessionIds) == 0 {
+		return gerror.NewCode(gcode.CodeInternalError, MsgErrDataEmpty)
+	}
+	for sessionId := range sessionIds {
+		_, err = m.updateSession(ctx, userKey, sessionId, func(userCache g.Map) error {
+			userCache[KeyData] = data
+			return nil
+		})
+		if err != nil {
+			return gerror.WrapCode(gcode.CodeInternalError, err)
+		}
+	}
+	return nil
+}
+
+// UpdateSessionData 更新单个会话数据，不重新生成token
+func (m *GTokenV2) UpdateSessionData(ctx context.Context, userKey, sessionId string, data any) error {
+	if userKey == "" {
+		return gerror.NewCode(gcode.CodeMissingParameter, MsgErrUserKeyEmpty)
+	}
+	if sessionId == "" {
+		return gerror.NewCode(gcode.CodeMissingParameter, MsgErrSessionIdEmpty)
+	}
+
+	userCache, err := m.updateSession(ctx, userKey, sessionId, func(userCache g.Map) error {
+		userCache[KeyData] = data
+		return nil
+	})
+	if err != nil {
+		return gerror.WrapCode(gcode.CodeInternalError, err)
+	}
+	if userCache == nil {
+		return gerror.NewCode(gcode.CodeInternalError, MsgErrDataEmpty)
+	}
 	return nil
 }
 
@@ -219,3 +485,531 @@ func (m *GTokenV2) Destroy(ctx context.Context, userKey string) error {
 func (m *GTokenV2) GetOptions() Options {
 	return m.Options
 }
+
+// createSession 创建会话缓存及access token，非多端登录时销毁已有会话
+func (m *GTokenV2) createSession(ctx context.Context, userKey string, data any) (userCache g.Map, token string, err error) {
+	if userKey == "" {
+		err = gerror.NewCode(gcode.CodeMissingParameter, MsgErrUserKeyEmpty)
+		return
+	}
+
+	if !m.Options.MultiLogin {
+		// 不支持多端登录，剔除已登录会话
+		err = m.kickOut(ctx, userKey)
+		if err != nil {
+			err = gerror.WrapCode(gcode.CodeInternalError, err)
+			return
+		}
+	}
+
+	sessionId, err := m.newSessionId(ctx)
+	if err != nil {
+		err = gerror.WrapCode(gcode.CodeInternalError, err)
+		return
+	}
+	token, err = m.Codec.Encode(ctx, userKey, sessionId)
+	if err != nil {
+		err = gerror.WrapCode(gcode.CodeInternalError, err)
+		return
+	}
+
+	nowTime := gtime.Now().TimestampMilli()
+	userCache = g.Map{
+		KeyUserKey:    userKey,
+		KeySessionId:  sessionId,
+		KeyData:       data,
+		KeyRefreshNum: 0,
+		KeyCreateTime: nowTime,
+		KeyLoginTime:  nowTime,
+		KeyLastSeen:   nowTime,
+		KeyDevice:     getDevice(ctx),
+	}
+	m.setToken(userCache, KeyToken, token)
+	return
+}
+
+// newSessionId 生成会话ID，未配置生成器时使用crypto/rand随机ID
+func (m *GTokenV2) newSessionId(ctx context.Context) (string, error) {
+	if m.IdGenerator == nil {
+		return RandomIdGenerator{Size: DefaultIdSize}.NewId(ctx)
+	}
+	return m.IdGenerator.NewId(ctx)
+}
+
+// issuePair 为会话签发新的 access token + refresh token
+func (m *GTokenV2) issuePair(ctx context.Context, userCache g.Map) (pair TokenPair, err error) {
+	var (
+		userKey   = gconv.String(userCache[KeyUserKey])
+		sessionId = gconv.String(userCache[KeySessionId])
+		nowTime   = gtime.Now().TimestampMilli()
+	)
+	accessToken, err := m.Codec.Encode(ctx, userKey, sessionId)
+	if err != nil {
+		err = gerror.WrapCode(gcode.CodeInternalError, err)
+		return
+	}
+	refreshToken, err := m.Codec.Encode(ctx, userKey, sessionId)
+	if err != nil {
+		err = gerror.WrapCode(gcode.CodeInternalError, err)
+		return
+	}
+
+	m.setToken(userCache, KeyToken, accessToken)
+	userCache[KeyCreateTime] = nowTime
+	m.setToken(userCache, KeyRefreshToken, refreshToken)
+	userCache[KeyRefreshTime] = nowTime
+	return TokenPair{
+		AccessToken:   accessToken,
+		RefreshToken:  refreshToken,
+		AccessExpire:  nowTime + m.Options.Timeout,
+		RefreshExpire: nowTime + m.Options.RefreshTimeout,
+	}, nil
+}
+
+// loadSession 通过token获取会话缓存，并校验token是否与会话tokenKey字段一致
+func (m *GTokenV2) loadSession(ctx context.Context, token, tokenKey string) (userCache g.Map, err error) {
+	userKey, sessionId, err := m.Codec.Decrypt(ctx, token)
+	if err != nil {
+		err = gerror.WrapCode(gcode.CodeInvalidParameter, err)
+		return
+	}
+	userCache, expireErr, err := m.getSession(ctx, userKey, sessionId)
+	if err == nil && userCache == nil && expireErr == nil {
+		// 升级前登录的会话
+		userCache, expireErr, err = m.migrateLegacy(ctx, userKey, sessionId, token)
+	}
+	if err != nil {
+		err = gerror.WrapCode(gcode.CodeInternalError, err)
+		return
+	}
+	if expireErr != nil {
+		err = expireErr
+		return
+	}
+	if userCache == nil {
+		// 会话被新登录剔除
+		kicked, e := m.isKickedOut(ctx, userKey, sessionId)
+		if e == nil && !kicked {
+			// 会话已过期并被清理
+			expireErr, e = m.getExpired(ctx, userKey, sessionId)
+		}
+		if e != nil {
+			err = gerror.WrapCode(gcode.CodeInternalError, e)
+		} else if kicked {
+			err = gerror.NewCode(CodeKickedOut, MsgErrKickedOut)
+		} else if expireErr != nil {
+			err = expireErr
+		} else {
+			err = gerror.NewCode(gcode.CodeInternalError, MsgErrDataEmpty)
+		}
+		return
+	}
+	// 重新签发前的token在下次续期前仍然有效
+	if !m.matchToken(userCache, tokenKey, token) && (tokenKey != KeyToken || !m.matchToken(userCache, KeyPrevToken, token)) {
+		err = gerror.NewCode(gcode.CodeInvalidParameter, MsgErrValidate)
+		return
+	}
+	// 双token会话，access token先于会话过期
+	if tokenKey == KeyToken && gtime.Now().TimestampMilli() > gconv.Int64(userCache[KeyCreateTime])+m.Options.Timeout {
+		err = gerror.NewCode(CodeTokenExpired, MsgErrTokenExpired)
+		return
+	}
+	return
+}
+
+// getSession 获取会话缓存；会话已过期时删除会话并保留过期记录，返回过期原因
+func (m *GTokenV2) getSession(ctx context.Context, userKey, sessionId string) (userCache g.Map, expireErr error, err error) {
+	userCache, err = m.Cache.Get(ctx, sessionCacheKey(userKey, sessionId))
+	if err != nil || userCache == nil {
+		return nil, nil, err
+	}
+	if expireErr = m.expireError(userCache); expireErr != nil {
+		if err = m.expireSession(ctx, userKey, sessionId, expireErr); err != nil {
+			return nil, nil, err
+		}
+		return nil, expireErr, m.removeSession(ctx, userKey, sessionId)
+	}
+	return userCache, nil, nil
+}
+
+// expireSession 保留过期记录，会话被其他请求清理后，后续请求仍返回过期原因
+func (m *GTokenV2) expireSession(ctx context.Context, userKey, sessionId string, expireErr error) error {
+	nowTime := gtime.Now().TimestampMilli()
+	return m.Cache.Set(ctx, expiredCacheKey(userKey, sessionId), g.Map{
+		KeyUserKey:    userKey,
+		KeySessionId:  sessionId,
+		KeyExpireCode: gerror.Code(expireErr).Code(),
+		KeyCreateTime: nowTime,
+		KeyExpireTime: nowTime + DefaultExpiredKeep,
+	})
+}
+
+// getExpired 获取已清理会话的过期原因，无记录时返回nil
+func (m *GTokenV2) getExpired(ctx context.Context, userKey, sessionId string) (expireErr error, err error) {
+	expired, err := m.Cache.Get(ctx, expiredCacheKey(userKey, sessionId))
+	if err != nil || expired == nil {
+		return nil, err
+	}
+	if gtime.Now().TimestampMilli() > gconv.Int64(expired[KeyExpireTime]) {
+		return nil, m.Cache.Remove(ctx, expiredCacheKey(userKey, sessionId))
+	}
+	switch gconv.Int(expired[KeyExpireCode]) {
+	case CodeLifetimeExceeded.Code():
+		return gerror.NewCode(CodeLifetimeExceeded, MsgErrLifetimeExceeded), nil
+	case CodeIdleTimeout.Code():
+		return gerror.NewCode(CodeIdleTimeout, MsgErrIdleTimeout), nil
+	default:
+		return gerror.NewCode(CodeTokenExpired, MsgErrTokenExpired), nil
+	}
+}
+
+// expireError 判断会话是否过期，返回过期原因
+func (m *GTokenV2) expireError(userCache g.Map) error {
+	nowTime := gtime.Now().TimestampMilli()
+	// 绝对有效期，从首次登录时间起算，刷新不延长
+	if m.Options.MaxLifetime > 0 && nowTime > gconv.Int64(userCache[KeyLoginTime])+m.Options.MaxLifetime {
+		return gerror.NewCode(CodeLifetimeExceeded, MsgErrLifetimeExceeded)
+	}
+	// 空闲超时，从最后访问时间起算
+	if m.Options.IdleTimeout > 0 && nowTime > gconv.Int64(userCache[KeyLastSeen])+m.Options.IdleTimeout {
+		return gerror.NewCode(CodeIdleTimeout, MsgErrIdleTimeout)
+	}
+	if nowTime > m.timeoutTime(userCache) {
+		return gerror.NewCode(CodeTokenExpired, MsgErrTokenExpired)
+	}
+	return nil
+}
+
+// timeoutTime 会话超时时间
+func (m *GTokenV2) timeoutTime(userCache g.Map) int64 {
+	// 缓存写入会延长缓存有效期，以创建时间判断是否超时
+	if m.hasToken(userCache, KeyRefreshToken) {
+		// 双token会话以refresh token创建时间判断是否超时
+		return gconv.Int64(userCache[KeyRefreshTime]) + m.Options.RefreshTimeout
+	}
+	return gconv.Int64(userCache[KeyCreateTime]) + m.Options.Timeout
+}
+
+// expireTime 会话剩余有效期的截止时间，取超时、空闲超时、最长有效期中最早者
+func (m *GTokenV2) expireTime(userCache g.Map) int64 {
+	expireTime := m.timeoutTime(userCache)
+	if m.Options.IdleTimeout > 0 {
+		expireTime = min(expireTime, gconv.Int64(userCache[KeyLastSeen])+m.Options.IdleTimeout)
+	}
+	if m.Options.MaxLifetime > 0 {
+		expireTime = min(expireTime, gconv.Int64(userCache[KeyLoginTime])+m.Options.MaxLifetime)
+	}
+	return expireTime
+}
+
+// kickOut 剔除用户全部会话
+func (m *GTokenV2) kickOut(ctx context.Context, userKey string) error {
+	sessions, err := m.getSessions(ctx, userKey)
+	if err != nil {
+		return err
+	}
+	for _, userCache := range sessions {
+		err = m.kickSession(ctx, userCache)
+		if err != nil {
+			return err
+		}
+	}
+	return m.Destroy(ctx, userKey)
+}
+
+// reserveSession 原子检查会话数并写入会话索引，会话数达到MaxSessions时按EvictPolicy剔除会话或拒绝登录
+// 会话缓存先于索引写入，索引中不存在会话缓存的会话已失效
+func (m *GTokenV2) reserveSession(ctx context.Context, userKey, sessionId string) error {
+	var evicted []g.Map
+	err := m.update(ctx, userCacheKey(userKey), func(sessionIds g.Map) (g.Map, error) {
+		evicted = nil
+		if sessionIds == nil {
+			sessionIds = g.Map{}
+		}
+		sessions := make([]g.Map, 0, len(sessionIds))
+		for id := range sessionIds {
+			userCache, err := m.Cache.Get(ctx, sessionCacheKey(userKey, id))
+			if err != nil {
+				return nil, err
+			}
+			// 已过期会话不计数，会话缓存保留至下次访问返回过期原因
+			if userCache == nil || m.expireError(userCache) != nil {
+				delete(sessionIds, id)
+				continue
+			}
+			sessions = append(sessions, userCache)
+		}
+
+		evictNum := len(sessions) - m.Options.MaxSessions + 1
+		if evictNum > 0 {
+			switch m.Options.EvictPolicy {
+			case EvictPolicyReject:
+				return nil, gerror.NewCode(CodeSessionLimit, MsgErrSessionLimit)
+			case EvictPolicyLRU:
+				// 按最后访问时间升序，最后访问时间按touchInterval间隔更新
+				sort.SliceStable(sessions, func(i, j int) bool {
+					return gconv.Int64(sessions[i][KeyLastSeen]) < gconv.Int64(sessions[j][KeyLastSeen])
+				})
+			default:
+				// 按登录时间升序
+				sort.SliceStable(sessions, func(i, j int) bool {
+					return gconv.Int64(sessionIds[gconv.String(sessions[i][KeySessionId])]) <
+						gconv.Int64(sessionIds[gconv.String(sessions[j][KeySessionId])])
+				})
+			}
+			evicted = sessions[:evictNum]
+			for _, userCache := range evicted {
+				delete(sessionIds, gconv.String(userCache[KeySessionId]))
+			}
+		}
+		sessionIds[sessionId] = gtime.Now().TimestampNano()
+		return sessionIds, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, userCache := range evicted {
+		err = m.kickSession(ctx, userCache)
+		if err != nil {
+			return err
+		}
+		err = m.Cache.Remove(ctx, sessionCacheKey(userKey, gconv.String(userCache[KeySessionId])))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// kickSession 在会话剩余有效期内保留剔除记录
+func (m *GTokenV2) kickSession(ctx context.Context, userCache g.Map) error {
+	var (
+		userKey   = gconv.String(userCache[KeyUserKey])
+		sessionId = gconv.String(userCache[KeySessionId])
+	)
+	return m.Cache.Set(ctx, kickedCacheKey(userKey, sessionId), g.Map{
+		KeyUserKey:    userKey,
+		KeySessionId:  sessionId,
+		KeyCreateTime: gtime.Now().TimestampMilli(),
+		KeyExpireTime: m.expireTime(userCache),
+	})
+}
+
+// isKickedOut 判断会话是否被新登录剔除
+func (m *GTokenV2) isKickedOut(ctx context.Context, userKey, sessionId string) (bool, error) {
+	kicked, err := m.Cache.Get(ctx, kickedCacheKey(userKey, sessionId))
+	if err != nil || kicked == nil {
+		return false, err
+	}
+	if gtime.Now().TimestampMilli() > gconv.Int64(kicked[KeyExpireTime]) {
+		return false, m.Cache.Remove(ctx, kickedCacheKey(userKey, sessionId))
+	}
+	return true, nil
+}
+
+// needRefresh 判断是否需要自动续期
+func (m *GTokenV2) needRefresh(userCache g.Map, nowTime int64) bool {
+	if m.Options.MaxRefresh == 0 {
+		return false
+	}
+	// 双token会话由客户端通过Refresh续期
+	if m.hasToken(userCache, KeyRefreshToken) {
+		return false
+	}
+	if m.Options.MaxRefreshTimes > 0 && gconv.Int(userCache[KeyRefreshNum]) >= m.Options.MaxRefreshTimes {
+		return false
+	}
+	return nowTime > gconv.Int64(userCache[KeyCreateTime])+m.Options.MaxRefresh
+}
+
+// needReissue 判断token是否需要使用当前密钥重新签发
+// 新token通过响应头返回，没有http请求时不重新签发
+func (m *GTokenV2) needReissue(ctx context.Context, token string) bool {
+	if !m.Options.ReissueToken || g.RequestFromCtx(ctx) == nil {
+		return false
+	}
+	reissuer, ok := m.Codec.(Reissuer)
+	return ok && reissuer.NeedReissue(ctx, token)
+}
+
+// touchInterval 最后访问时间写入间隔，开启空闲超时时不超过空闲超时时间的1/10
+func (m *GTokenV2) touchInterval() int64 {
+	interval := int64(DefaultTouchInterval)
+	if m.Options.IdleTimeout > 0 && m.Options.IdleTimeout/10 < interval {
+		interval = m.Options.IdleTimeout / 10
+	}
+	return interval
+}
+
+// revokeReused 判断refresh token是否已被轮换，已轮换则销毁其所属会话
+func (m *GTokenV2) revokeReused(ctx context.Context, refreshToken string) (bool, error) {
+	var (
+		rotated g.Map
+		err     error
+	)
+	for _, hash := range m.hashTokens(refreshToken) {
+		if rotated, err = m.Cache.Get(ctx, rotatedCacheKey(hash)); err != nil || rotated != nil {
+			break
+		}
+	}
+	if err != nil || rotated == nil {
+		return false, err
+	}
+	userKey := gconv.String(rotated[KeyUserKey])
+	sessionId := gconv.String(rotated[KeySessionId])
+	g.Log().Warning(ctx, "[GToken]refresh token reused, revoke session", userKey, sessionId)
+	return true, m.removeSession(ctx, userKey, sessionId)
+}
+
+// saveSession 保存新会话缓存，并写入会话索引，保证索引不早于会话过期
+// 多端登录限制会话数时，会话数检查与索引写入在同一原子更新中完成，拒绝登录时删除会话缓存
+func (m *GTokenV2) saveSession(ctx context.Context, userCache g.Map) error {
+	var (
+		userKey   = gconv.String(userCache[KeyUserKey])
+		sessionId = gconv.String(userCache[KeySessionId])
+	)
+	err := m.Cache.Set(ctx, sessionCacheKey(userKey, sessionId), userCache)
+	if err != nil {
+		return err
+	}
+	if !m.Options.MultiLogin || m.Options.MaxSessions <= 0 {
+		return m.addSessionId(ctx, userKey, sessionId)
+	}
+	if err = m.reserveSession(ctx, userKey, sessionId); err != nil {
+		if e := m.Cache.Remove(ctx, sessionCacheKey(userKey, sessionId)); e != nil {
+			g.Log().Error(ctx, "[GToken]remove rejected session error", e)
+		}
+	}
+	return err
+}
+
+// updateSession 原子更新会话缓存，会话不存在时返回nil
+func (m *GTokenV2) updateSession(ctx context.Context, userKey, sessionId string, f func(userCache g.Map) error) (userCache g.Map, err error) {
+	err = m.update(ctx, sessionCacheKey(userKey, sessionId), func(cacheValue g.Map) (g.Map, error) {
+		userCache = cacheValue
+		if cacheValue == nil {
+			return nil, nil
+		}
+		m.migrateTokens(cacheValue)
+		return cacheValue, f(cacheValue)
+	})
+	if err != nil || userCache == nil {
+		return
+	}
+	err = m.addSessionId(ctx, userKey, sessionId)
+	return
+}
+
+// removeSession 删除会话缓存及会话索引
+func (m *GTokenV2) removeSession(ctx context.Context, userKey, sessionId string) error {
+	err := m.Cache.Remove(ctx, sessionCacheKey(userKey, sessionId))
+	if err != nil {
+		return err
+	}
+	return m.removeSessionIds(ctx, userKey, sessionId)
+}
+
+// update 原子更新缓存
+func (m *GTokenV2) update(ctx context.Context, cacheKey string, f func(cacheValue g.Map) (g.Map, error)) error {
+	return UpdateCacheValue(ctx, m.Cache, cacheKey, f)
+}
+
+// getSessionIds 获取用户会话索引 sessionId => 登录时间（纳秒）
+func (m *GTokenV2) getSessionIds(ctx context.Context, userKey string) (g.Map, error) {
+	sessionIds, err := m.Cache.Get(ctx, userCacheKey(userKey))
+	if err != nil {
+		return nil, err
+	}
+	if sessionIds == nil {
+		sessionIds = g.Map{}
+	}
+	return sessionIds, nil
+}
+
+// getSessions 获取用户全部有效会话，按登录时间升序，并清理索引中已过期的会话
+func (m *GTokenV2) getSessions(ctx context.Context, userKey string) ([]g.Map, error) {
+	sessionIds, err := m.getSessionIds(ctx, userKey)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		sessions = make([]g.Map, 0, len(sessionIds))
+		expired  = make([]string, 0)
+	)
+	for sessionId := range sessionIds {
+		userCache, _, err := m.getSession(ctx, userKey, sessionId)
+		if err != nil {
+			return nil, err
+		}
+		if userCache == nil {
+			expired = append(expired, sessionId)
+			continue
+		}
+		sessions = append(sessions, userCache)
+	}
+	if len(expired) > 0 {
+		if err = m.removeSessionIds(ctx, userKey, expired...); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return gconv.Int64(sessionIds[gconv.String(sessions[i][KeySessionId])]) <
+			gconv.Int64(sessionIds[gconv.String(sessions[j][KeySessionId])])
+	})
+	return sessions, nil
+}
+
+// addSessionId 写入会话索引；会话已存在时仅刷新索引有效期
+func (m *GTokenV2) addSessionId(ctx context.Context, userKey, sessionId string) error {
+	return m.update(ctx, userCacheKey(userKey), func(sessionIds g.Map) (g.Map, error) {
+		if sessionIds == nil {
+			sessionIds = g.Map{}
+		}
+		if sessionIds[sessionId] == nil {
+			sessionIds[sessionId] = gtime.Now().TimestampNano()
+		}
+		return sessionIds, nil
+	})
+}
+
+// removeSessionIds 删除会话索引，索引为空时删除索引缓存
+func (m *GTokenV2) removeSessionIds(ctx context.Context, userKey string, sessionIds ...string) error {
+	return m.update(ctx, userCacheKey(userKey), func(cacheValue g.Map) (g.Map, error) {
+		if cacheValue == nil {
+			return nil, nil
+		}
+		for _, sessionId := range sessionIds {
+			delete(cacheValue, sessionId)
+		}
+		if len(cacheValue) == 0 {
+			return nil, nil
+		}
+		return cacheValue, nil
+	})
+}
+
+// userCacheKey 用户会话索引缓存key
+func userCacheKey(userKey string) string {
+	return CacheKeyUser + userKey
+}
+
+// sessionCacheKey 会话缓存key
+func sessionCacheKey(userKey, sessionId string) string {
+	return CacheKeySession + userKey + ":" + sessionId
+}
+
+// kickedCacheKey 被剔除会话缓存key
+func kickedCacheKey(userKey, sessionId string) string {
+	return CacheKeyKicked + userKey + ":" + sessionId
+}
+
+// expiredCacheKey 过期会话缓存key
+func expiredCacheKey(userKey, sessionId string) string {
+	return CacheKeyExpired + userKey + ":" + sessionId
+}
+
+// rotatedCacheKey 已轮换refresh token缓存key，使用token摘要
+func rotatedCacheKey(tokenHash string) string {
+	return CacheKeyRotated + tokenHash
+}
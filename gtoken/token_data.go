@@ -0,0 +1,55 @@
+package gtoken
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+// GetData 通过userKey获取token及指定类型的数据
+// 数据经过json编解码转换，与缓存、jwt等不同实现保持一致，字段名以json tag为准
+// 缓存未保存token原文时，返回数据及CodeTokenNotStored错误
+func GetData[T any](ctx context.Context, t Token, userKey string) (token string, data T, err error) {
+	token, value, err := t.Get(ctx, userKey)
+	if err != nil && !IsTokenNotStored(err) {
+		return
+	}
+	data, e := decodeData[T](value)
+	if e != nil {
+		err = e
+	}
+	return
+}
+
+// ParseTokenAs 通过token获取userKey及指定类型的数据
+// 数据经过json编解码转换，与缓存、jwt等不同实现保持一致，字段名以json tag为准
+func ParseTokenAs[T any](ctx context.Context, t Token, token string) (userKey string, data T, err error) {
+	userKey, value, err := t.ParseToken(ctx, token)
+	if err != nil {
+		return
+	}
+	data, err = decodeData[T](value)
+	return
+}
+
+// decodeData 将数据转换为指定类型
+func decodeData[T any](value any) (data T, err error) {
+	if value == nil {
+		return
+	}
+	if v, ok := value.(T); ok {
+		return v, nil
+	}
+	content, err := gjson.Encode(value)
+	if err != nil {
+		err = gerror.WrapCode(gcode.CodeInternalError, err)
+		return
+	}
+	err = gjson.DecodeTo(content, &data)
+	if err != nil {
+		err = gerror.WrapCode(gcode.CodeInternalError, err)
+		return
+	}
+	return
+}
@@ -0,0 +1,67 @@
+package gtoken_test
+
+import (
+	"github.com/goflyfox/gtoken/v2/gtoken"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type testUserData struct {
+	Name  string   `json:"name"`
+	Roles []string `json:"roles"`
+	Age   int      `json:"age"`
+}
+
+func TestGetData(t *testing.T) {
+	var (
+		ctx     = gctx.New()
+		userKey = "testUser"
+		data    = testUserData{Name: "flyFox", Roles: []string{"admin"}, Age: 18}
+	)
+	for _, cacheMode := range []int8{gtoken.CacheModeCache, gtoken.CacheModeFile} {
+		gToken := gtoken.NewDefaultToken(gtoken.Options{
+			CacheMode:     cacheMode,
+			CachePreKey:   "GTokenData:",
+			StoreRawToken: true,
+		})
+		token, err := gToken.Generate(ctx, userKey, data)
+		assert.NoError(t, err)
+
+		token2, data2, err := gtoken.GetData[testUserData](ctx, gToken, userKey)
+		assert.NoError(t, err)
+		assert.Equal(t, token, token2)
+		assert.Equal(t, data, data2)
+
+		userKey2, data3, err := gtoken.ParseTokenAs[*testUserData](ctx, gToken, token)
+		assert.NoError(t, err)
+		assert.Equal(t, userKey, userKey2)
+		assert.Equal(t, &data, data3)
+
+		// 转换为map
+		_, data4, err := gtoken.ParseTokenAs[g.Map](ctx, gToken, token)
+		assert.NoError(t, err)
+		assert.Equal(t, "flyFox", data4["name"])
+
+		// 无数据返回零值
+		token, err = gToken.Generate(ctx, userKey, nil)
+		assert.NoError(t, err)
+		_, data5, err := gtoken.ParseTokenAs[testUserData](ctx, gToken, token)
+		assert.NoError(t, err)
+		assert.Equal(t, testUserData{}, data5)
+
+		_, _, err = gtoken.ParseTokenAs[testUserData](ctx, gToken, "123")
+		assert.Error(t, err)
+		assert.NoError(t, gToken.Destroy(ctx, userKey))
+	}
+
+	// 缓存仅保存token摘要时仍返回数据
+	gToken := gtoken.NewDefaultToken(gtoken.Options{})
+	_, err := gToken.Generate(ctx, userKey, data)
+	assert.NoError(t, err)
+	token, data2, err := gtoken.GetData[testUserData](ctx, gToken, userKey)
+	assert.True(t, gtoken.IsTokenNotStored(err))
+	assert.Empty(t, token)
+	assert.Equal(t, data, data2)
+}
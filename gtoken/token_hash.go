@@ -0,0 +1,105 @@
+package gtoken
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+// tokenHashKeys token缓存字段对应的摘要字段
+var tokenHashKeys = map[string]string{
+	KeyToken:        KeyTokenHash,
+	KeyRefreshToken: KeyRefreshTokenHash,
+	KeyPrevToken:    KeyPrevTokenHash,
+}
+
+const tokenHashLabel = "gtoken-token-hash" // 摘要密钥派生标识
+
+// IsTokenNotStored 判断Get未返回token是否由于缓存仅保存token摘要
+func IsTokenNotStored(err error) bool {
+	return gerror.Code(err).Code() == CodeTokenNotStored.Code()
+}
+
+// hashKeys token摘要密钥，当前密钥在前
+// 未配置TokenHashKey时由EncryptKey及DecryptKeys派生，密钥轮换期间已缓存的摘要仍可验证
+func (m *GTokenV2) hashKeys() [][]byte {
+	if len(m.Options.TokenHashKey) > 0 {
+		return [][]byte{m.Options.TokenHashKey}
+	}
+	keys := [][]byte{hmacSum(m.Options.EncryptKey, tokenHashLabel)}
+	for _, key := range m.Options.DecryptKeys {
+		keys = append(keys, hmacSum(key.Key, tokenHashLabel))
+	}
+	return keys
+}
+
+// hashToken token的HMAC-SHA256摘要，同时用于缓存key，避免缓存中出现token原文
+func (m *GTokenV2) hashToken(token string) string {
+	return hex.EncodeToString(hmacSum(m.hashKeys()[0], token))
+}
+
+// hashTokens 使用全部摘要密钥计算token摘要
+func (m *GTokenV2) hashTokens(token string) []string {
+	keys := m.hashKeys()
+	hashes := make([]string, 0, len(keys))
+	for _, key := range keys {
+		hashes = append(hashes, hex.EncodeToString(hmacSum(key, token)))
+	}
+	return hashes
+}
+
+func hmacSum(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// setToken 缓存token摘要，开启StoreRawToken时同时缓存token原文
+func (m *GTokenV2) setToken(userCache g.Map, tokenKey, token string) {
+	userCache[tokenHashKeys[tokenKey]] = m.hashToken(token)
+	if m.Options.StoreRawToken {
+		userCache[tokenKey] = token
+	} else {
+		delete(userCache, tokenKey)
+	}
+}
+
+// matchToken 常量时间比较token摘要；兼容升级前仅缓存token原文的会话
+func (m *GTokenV2) matchToken(userCache g.Map, tokenKey, token string) bool {
+	if hash := gconv.String(userCache[tokenHashKeys[tokenKey]]); hash != "" {
+		matched := 0
+		for _, h := range m.hashTokens(token) {
+			matched |= subtle.ConstantTimeCompare([]byte(h), []byte(hash))
+		}
+		return matched == 1
+	}
+	raw := gconv.String(userCache[tokenKey])
+	return raw != "" && subtle.ConstantTimeCompare([]byte(token), []byte(raw)) == 1
+}
+
+// hasToken 会话是否包含token
+func (m *GTokenV2) hasToken(userCache g.Map, tokenKey string) bool {
+	return userCache[tokenHashKeys[tokenKey]] != nil || userCache[tokenKey] != nil
+}
+
+// removeToken 移除token及摘要
+func (m *GTokenV2) removeToken(userCache g.Map, tokenKey string) {
+	delete(userCache, tokenKey)
+	delete(userCache, tokenHashKeys[tokenKey])
+}
+
+// migrateTokens 升级前缓存的token原文替换为摘要
+func (m *GTokenV2) migrateTokens(userCache g.Map) {
+	if m.Options.StoreRawToken {
+		return
+	}
+	for tokenKey := range tokenHashKeys {
+		if raw := gconv.String(userCache[tokenKey]); raw != "" {
+			m.setToken(userCache, tokenKey, raw)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package gtoken
+
+import (
+	"context"
+	"crypto/subtle"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+// getLegacy 获取v2.0.x会话缓存，旧会话以userKey为缓存key并保存token原文，token不一致时返回nil
+func (m *GTokenV2) getLegacy(ctx context.Context, userKey, token string) (g.Map, error) {
+	if m.Options.DisableLegacy {
+		return nil, nil
+	}
+	legacy, err := m.Cache.Get(ctx, userKey)
+	if err != nil || !isLegacy(legacy, userKey) {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(gconv.String(legacy[KeyToken])), []byte(token)) != 1 {
+		return nil, nil
+	}
+	return legacy, nil
+}
+
+// removeLegacy 删除v2.0.x会话缓存
+// 旧会话缓存key为userKey原文，可能与user:、session:等缓存key相同，仅删除旧格式缓存
+func (m *GTokenV2) removeLegacy(ctx context.Context, userKey string) error {
+	if m.Options.DisableLegacy {
+		return nil
+	}
+	legacy, err := m.Cache.Get(ctx, userKey)
+	if err != nil || !isLegacy(legacy, userKey) {
+		return err
+	}
+	return m.Cache.Remove(ctx, userKey)
+}
+
+// isLegacy 是否为v2.0.x会话缓存：userKey一致，保存token原文且没有会话ID
+func isLegacy(legacy g.Map, userKey string) bool {
+	return legacy != nil && gconv.String(legacy[KeyUserKey]) == userKey &&
+		gconv.String(legacy[KeyToken]) != "" && legacy[KeySessionId] == nil
+}
+
+// migrateLegacy 将v2.0.x会话迁移为独立会话，升级后已登录用户无需重新登录
+// 旧格式token以随机串作为会话ID，迁移后通过会话ID获取会话
+func (m *GTokenV2) migrateLegacy(ctx context.Context, userKey, sessionId, token string) (userCache g.Map, expireErr error, err error) {
+	legacy, err := m.getLegacy(ctx, userKey, token)
+	if err != nil || legacy == nil {
+		return nil, nil, err
+	}
+	createTime := gconv.Int64(legacy[KeyCreateTime])
+	userCache = g.Map{
+		KeyUserKey:    userKey,
+		KeySessionId:  sessionId,
+		KeyData:       legacy[KeyData],
+		KeyRefreshNum: gconv.Int(legacy[KeyRefreshNum]),
+		KeyCreateTime: createTime,
+		KeyLoginTime:  createTime,
+		KeyLastSeen:   gtime.Now().TimestampMilli(),
+		KeyDevice:     getDevice(ctx),
+	}
+	m.setToken(userCache, KeyToken, token)
+	if expireErr = m.expireError(userCache); expireErr == nil {
+		if err = m.saveSession(ctx, userCache); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		userCache = nil
+	}
+	return userCache, expireErr, m.Cache.Remove(ctx, userKey)
+}
@@ -8,19 +8,36 @@ import (
 type Options struct {
 	CacheMode        int8       // 缓存模式 1 gcache 2 gredis 3 gfile 默认1
 	CachePreKey      string     // 缓存key前缀
+	CacheEncryptKey  []byte     // 缓存数据加密key（16、24或32字节），配置后会话数据加密存储，需与EncryptKey不同
 	Timeout          int64      // 超时时间 默认10天（毫秒）
 	MaxRefresh       int64      // 缓存刷新时间 默认为超时时间的一半（毫秒）
 	MaxRefreshTimes  int        // 最大刷新次数 默认0 不限制
-	TokenDelimiter   string     // Token分隔符
+	RefreshTimeout   int64      // refresh token超时时间，大于0时支持双token（毫秒）
+	IdleTimeout      int64      // 空闲超时时间，超过该时间无请求则会话过期，默认0不限制（毫秒）
+	MaxLifetime      int64      // 会话最长有效期，从登录时间起算，刷新不延长，默认0不限制（毫秒）
+	TokenDelimiter   string     // Token分隔符，仅用于解析v2.0.x旧格式token
+	DisableLegacy    bool       // 是否拒绝v2.0.x旧格式token，默认false
 	EncryptKey       []byte     // Token加密key
+	TokenPrefix      string     // Token前缀，配置后token格式为：前缀_URL安全base64_crc32，默认空
+	EncryptKid       string     // Token加密key ID，密钥轮换时用于区分密钥
+	DecryptKeys      []CodecKey // 仅用于解密的旧密钥，更换EncryptKey后已签发的token仍然有效
+	ReissueToken     bool       // 自动续期时，未使用当前密钥加密的token重新签发，通过X-Renew-Token响应头返回
+	TokenHashKey     []byte     // 缓存token摘要HMAC-SHA256密钥，默认由EncryptKey派生
+	StoreRawToken    bool       // 缓存同时保存token原文，兼容Get返回token，默认false
 	MultiLogin       bool       // 是否支持多端登录，默认false
+	MaxSessions      int        // 多端登录时每个用户最大会话数，默认0不限制
+	EvictPolicy      int8       // 会话数超限策略 1 剔除最早登录 2 剔除最久未访问 3 拒绝新登录 默认1
+	IdMode           int8       // 会话ID生成方式 1 crypto/rand随机 2 UUIDv7 3 ULID 默认1
+	IdSize           int        // 随机会话ID字节数，默认16，最小8
 	AuthExcludePaths g.SliceStr // 拦截排除地址
 }
 
 func (o *Options) String() string {
 	return fmt.Sprintf("Options{"+
-		"CacheMode:%d, CachePreKey:%s, Timeout:%d, MaxRefresh:%d"+
-		", TokenDelimiter:%s, MultiLogin:%v, AuthExcludePaths:%v"+
-		"}", o.CacheMode, o.CachePreKey, o.Timeout, o.MaxRefresh,
-		o.TokenDelimiter, o.MultiLogin, o.AuthExcludePaths)
+		"CacheMode:%d, CachePreKey:%s, CacheEncrypt:%v, Timeout:%d, MaxRefresh:%d"+
+		", MaxRefreshTimes:%d, RefreshTimeout:%d, IdleTimeout:%d, MaxLifetime:%d"+
+		", TokenDelimiter:%s, DisableLegacy:%v, TokenPrefix:%s, EncryptKid:%s, DecryptKeys:%d, ReissueToken:%v, StoreRawToken:%v, MultiLogin:%v, MaxSessions:%d, EvictPolicy:%d, IdMode:%d, IdSize:%d, AuthExcludePaths:%v"+
+		"}", o.CacheMode, o.CachePreKey, len(o.CacheEncryptKey) > 0, o.Timeout, o.MaxRefresh,
+		o.MaxRefreshTimes, o.RefreshTimeout, o.IdleTimeout, o.MaxLifetime,
+		o.TokenDelimiter, o.DisableLegacy, o.TokenPrefix, o.EncryptKid, len(o.DecryptKeys), o.ReissueToken, o.StoreRawToken, o.MultiLogin, o.MaxSessions, o.EvictPolicy, o.IdMode, o.IdSize, o.AuthExcludePaths)
 }
@@ -1,11 +1,27 @@
 package gtoken_test
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"github.com/goflyfox/gtoken/v2/gtoken"
+	"github.com/gogf/gf/v2/crypto/gaes"
+	"github.com/gogf/gf/v2/crypto/gmd5"
+	"github.com/gogf/gf/v2/encoding/gbase64"
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
 	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
 	"github.com/gogf/gf/v2/os/gctx"
 	"github.com/gogf/gf/v2/os/glog"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/gogf/gf/v2/util/grand"
+	"github.com/gogf/gf/v2/util/guid"
 	"github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -22,16 +38,49 @@ func TestGenerate(t *testing.T) {
 		assert.NoError(t, err)
 		assert.NotEqual(t, token1, token2)
 	}
-	// 支持多端登陆
+	// 非多端登陆，旧Token失效
+	{
+		gToken := gtoken.NewDefaultToken(gtoken.Options{})
+		token1, err := gToken.Generate(ctx, userKey, nil)
+		assert.NoError(t, err)
+		token2, err := gToken.Generate(ctx, userKey, nil)
+		assert.NoError(t, err)
+		_, err = gToken.Validate(ctx, token1)
+		assert.Error(t, err)
+		_, err = gToken.Validate(ctx, token2)
+		assert.NoError(t, err)
+	}
+	// 支持多端登陆，每次登录独立会话
 	{
 		gToken := gtoken.NewDefaultToken(gtoken.Options{
 			MultiLogin: true,
 		})
-		token1, err := gToken.Generate(ctx, userKey, nil)
+		token1, err := gToken.Generate(ctx, userKey, g.Map{"device": "phone"})
 		assert.NoError(t, err)
-		token2, err := gToken.Generate(ctx, userKey, nil)
+		token2, err := gToken.Generate(ctx, userKey, g.Map{"device": "web"})
+		assert.NoError(t, err)
+		assert.NotEqual(t, token1, token2)
+
+		_, data1, err := gToken.ParseToken(ctx, token1)
+		assert.NoError(t, err)
+		assert.Equal(t, g.Map{"device": "phone"}, data1)
+		_, data2, err := gToken.ParseToken(ctx, token2)
 		assert.NoError(t, err)
-		assert.Equal(t, token1, token2)
+		assert.Equal(t, g.Map{"device": "web"}, data2)
+
+		// Get返回最近一次登录会话，缓存仅保存token摘要，不返回token
+		token, data, err := gToken.Get(ctx, userKey)
+		assert.True(t, gtoken.IsTokenNotStored(err))
+		assert.Empty(t, token)
+		assert.Equal(t, data2, data)
+
+		// Destroy销毁全部会话
+		err = gToken.Destroy(ctx, userKey)
+		assert.NoError(t, err)
+		_, err = gToken.Validate(ctx, token1)
+		assert.Error(t, err)
+		_, err = gToken.Validate(ctx, token2)
+		assert.Error(t, err)
 	}
 }
 
@@ -97,10 +146,20 @@ func TestGet(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, userKey, u)
 		token2, data2, err := gToken.Get(ctx, userKey)
+		assert.Equal(t, gtoken.CodeTokenNotStored, gerror.Code(err))
+		assert.Empty(t, token2)
+		assert.Equal(t, data, data2)
+	}
+	// 缓存保存token原文，Get返回token
+	{
+		gToken := gtoken.NewDefaultToken(gtoken.Options{StoreRawToken: true})
+		data := g.Map{"a": "1"}
+		token, err := gToken.Generate(ctx, userKey, data)
+		assert.NoError(t, err)
+		token2, data2, err := gToken.Get(ctx, userKey)
 		assert.NoError(t, err)
 		assert.Equal(t, token, token2)
 		assert.Equal(t, data, data2)
-
 	}
 	{
 		gToken := gtoken.NewDefaultToken(gtoken.Options{})
@@ -174,3 +233,684 @@ func TestRefresh(t *testing.T) {
 
 	}
 }
+
+func TestSessions(t *testing.T) {
+	ctx := gctx.New()
+	userKey := "testUser"
+	gToken := gtoken.NewDefaultToken(gtoken.Options{
+		MultiLogin: true,
+	})
+	token1, err := gToken.Generate(ctx, userKey, nil)
+	assert.NoError(t, err)
+	token2, err := gToken.Generate(ctx, userKey, nil)
+	assert.NoError(t, err)
+	token3, err := gToken.Generate(ctx, userKey, nil)
+	assert.NoError(t, err)
+
+	// 会话列表按登录时间升序
+	sessions, err := gToken.Sessions(ctx, userKey)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 3)
+	for _, session := range sessions {
+		assert.Equal(t, userKey, session.UserKey)
+		assert.NotEmpty(t, session.SessionId)
+		assert.NotZero(t, session.LoginTime)
+		assert.NotZero(t, session.LastSeen)
+	}
+
+	// 通过token销毁单个会话
+	err = gToken.DestroyToken(ctx, token1)
+	assert.NoError(t, err)
+	_, err = gToken.Validate(ctx, token1)
+	assert.Error(t, err)
+	_, err = gToken.Validate(ctx, token2)
+	assert.NoError(t, err)
+
+	// 通过sessionId销毁单个会话
+	err = gToken.DestroySession(ctx, userKey, sessions[1].SessionId)
+	assert.NoError(t, err)
+	_, err = gToken.Validate(ctx, token2)
+	assert.Error(t, err)
+	_, err = gToken.Validate(ctx, token3)
+	assert.NoError(t, err)
+
+	sessions, err = gToken.Sessions(ctx, userKey)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+
+	err = gToken.Destroy(ctx, userKey)
+	assert.NoError(t, err)
+	sessions, err = gToken.Sessions(ctx, userKey)
+	assert.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+func TestTokenPair(t *testing.T) {
+	var (
+		ctx     = gctx.New()
+		userKey = "testUser"
+		data    = g.Map{"a": "1"}
+	)
+	// 未开启双token
+	{
+		gToken := gtoken.NewDefaultToken(gtoken.Options{})
+		_, err := gToken.GeneratePair(ctx, userKey, data)
+		assert.Error(t, err)
+		_, err = gToken.Refresh(ctx, "123")
+		assert.Error(t, err)
+	}
+	// access token过期后通过refresh token换取新token
+	{
+		gToken := gtoken.NewDefaultToken(gtoken.Options{
+			Timeout:        500,
+			MaxRefresh:     200,
+			RefreshTimeout: 2000,
+		})
+		pair, err := gToken.GeneratePair(ctx, userKey, data)
+		assert.NoError(t, err)
+		assert.NotEqual(t, pair.AccessToken, pair.RefreshToken)
+		u, err := gToken.Validate(ctx, pair.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, userKey, u)
+		// refresh token不能作为access token使用
+		_, err = gToken.Validate(ctx, pair.RefreshToken)
+		assert.Error(t, err)
+
+		// access token过期，不自动续期
+		time.Sleep(600 * time.Millisecond)
+		_, err = gToken.Validate(ctx, pair.AccessToken)
+		assert.Error(t, err)
+
+		pair2, err := gToken.Refresh(ctx, pair.RefreshToken)
+		assert.NoError(t, err)
+		assert.NotEqual(t, pair.AccessToken, pair2.AccessToken)
+		assert.NotEqual(t, pair.RefreshToken, pair2.RefreshToken)
+		_, data2, err := gToken.ParseToken(ctx, pair2.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, data, data2)
+
+		// refresh token过期
+		time.Sleep(2100 * time.Millisecond)
+		_, err = gToken.Refresh(ctx, pair2.RefreshToken)
+		assert.Error(t, err)
+	}
+	// 超过最大刷新次数
+	{
+		gToken := gtoken.NewDefaultToken(gtoken.Options{
+			Timeout:         500,
+			RefreshTimeout:  2000,
+			MaxRefreshTimes: 1,
+		})
+		pair, err := gToken.GeneratePair(ctx, userKey, data)
+		assert.NoError(t, err)
+		pair, err = gToken.Refresh(ctx, pair.RefreshToken)
+		assert.NoError(t, err)
+		_, err = gToken.Refresh(ctx, pair.RefreshToken)
+		assert.Error(t, err)
+	}
+}
+
+func TestRefreshReuse(t *testing.T) {
+	var (
+		ctx     = gctx.New()
+		userKey = "testUser"
+	)
+	for _, cacheMode := range []int8{gtoken.CacheModeCache, gtoken.CacheModeFile} {
+		gToken := gtoken.NewDefaultToken(gtoken.Options{
+			CacheMode:      cacheMode,
+			CachePreKey:    "GTokenReuse:",
+			MultiLogin:     true,
+			RefreshTimeout: 60 * 1000,
+		})
+		pair1, err := gToken.GeneratePair(ctx, userKey, nil)
+		assert.NoError(t, err)
+		other, err := gToken.GeneratePair(ctx, userKey, nil)
+		assert.NoError(t, err)
+
+		pair2, err := gToken.Refresh(ctx, pair1.RefreshToken)
+		assert.NoError(t, err)
+		_, err = gToken.Validate(ctx, pair2.AccessToken)
+		assert.NoError(t, err)
+
+		// 重用已轮换的refresh token，销毁整个会话
+		_, err = gToken.Refresh(ctx, pair1.RefreshToken)
+		assert.Error(t, err)
+		assert.Equal(t, gcode.CodeSecurityReason.Code(), gerror.Code(err).Code())
+		_, err = gToken.Validate(ctx, pair2.AccessToken)
+		assert.Error(t, err)
+		_, err = gToken.Refresh(ctx, pair2.RefreshToken)
+		assert.Error(t, err)
+
+		// 其他会话不受影响
+		_, err = gToken.Validate(ctx, other.AccessToken)
+		assert.NoError(t, err)
+		assert.NoError(t, gToken.Destroy(ctx, userKey))
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	var (
+		ctx     = gctx.New()
+		userKey = "testUser"
+	)
+	gToken := gtoken.NewDefaultToken(gtoken.Options{
+		IdleTimeout: 500,
+	})
+	token, err := gToken.Generate(ctx, userKey, nil)
+	assert.NoError(t, err)
+	// 持续访问不过期
+	for i := 0; i < 4; i++ {
+		time.Sleep(300 * time.Millisecond)
+		_, err = gToken.Validate(ctx, token)
+		assert.NoError(t, err)
+	}
+	// 空闲超时
+	time.Sleep(600 * time.Millisecond)
+	_, err = gToken.Validate(ctx, token)
+	assert.Error(t, err)
+	assert.Equal(t, gtoken.CodeIdleTimeout.Code(), gerror.Code(err).Code())
+
+	// 会话被查询清理后，仍返回过期原因
+	token, err = gToken.Generate(ctx, userKey, nil)
+	assert.NoError(t, err)
+	time.Sleep(600 * time.Millisecond)
+	sessions, err := gToken.Sessions(ctx, userKey)
+	assert.NoError(t, err)
+	assert.Empty(t, sessions)
+	_, err = gToken.Validate(ctx, token)
+	assert.Equal(t, gtoken.CodeIdleTimeout.Code(), gerror.Code(err).Code())
+	_, err = gToken.Validate(ctx, token)
+	assert.Equal(t, gtoken.CodeIdleTimeout.Code(), gerror.Code(err).Code())
+}
+
+func TestMaxLifetime(t *testing.T) {
+	var (
+		ctx     = gctx.New()
+		userKey = "testUser"
+	)
+	gToken := gtoken.NewDefaultToken(gtoken.Options{
+		Timeout:     500,
+		MaxRefresh:  100,
+		MaxLifetime: 1000,
+	})
+	token, err := gToken.Generate(ctx, userKey, nil)
+	assert.NoError(t, err)
+	// 自动续期，但不超过最长有效期
+	for i := 0; i < 3; i++ {
+		time.Sleep(300 * time.Millisecond)
+		_, err = gToken.Validate(ctx, token)
+		assert.NoError(t, err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	_, err = gToken.Validate(ctx, token)
+	assert.Error(t, err)
+	assert.Equal(t, gtoken.CodeLifetimeExceeded.Code(), gerror.Code(err).Code())
+}
+
+func TestKickedOut(t *testing.T) {
+	var (
+		ctx     = gctx.New()
+		userKey = "testUser"
+	)
+	gToken := gtoken.NewDefaultToken(gtoken.Options{})
+	token1, err := gToken.Generate(ctx, userKey, nil)
+	assert.NoError(t, err)
+	token2, err := gToken.Generate(ctx, userKey, nil)
+	assert.NoError(t, err)
+
+	// 旧会话被新登录剔除
+	_, err = gToken.Validate(ctx, token1)
+	assert.Error(t, err)
+	assert.True(t, gtoken.IsKickedOut(err))
+	_, err = gToken.Validate(ctx, token2)
+	assert.NoError(t, err)
+
+	// 主动登出不属于剔除
+	err = gToken.Destroy(ctx, userKey)
+	assert.NoError(t, err)
+	_, err = gToken.Validate(ctx, token2)
+	assert.Error(t, err)
+	assert.False(t, gtoken.IsKickedOut(err))
+}
+
+func TestMaxSessions(t *testing.T) {
+	var (
+		ctx     = gctx.New()
+		userKey = "testUser"
+	)
+	// 剔除最早登录会话
+	{
+		gToken := gtoken.NewDefaultToken(gtoken.Options{
+			MultiLogin:  true,
+			MaxSessions: 2,
+		})
+		token1, err := gToken.Generate(ctx, userKey, nil)
+		assert.NoError(t, err)
+		token2, err := gToken.Generate(ctx, userKey, nil)
+		assert.NoError(t, err)
+		token3, err := gToken.Generate(ctx, userKey, nil)
+		assert.NoError(t, err)
+
+		_, err = gToken.Validate(ctx, token1)
+		assert.True(t, gtoken.IsKickedOut(err))
+		_, err = gToken.Validate(ctx, token2)
+		assert.NoError(t, err)
+		_, err = gToken.Validate(ctx, token3)
+		assert.NoError(t, err)
+		sessions, err := gToken.Sessions(ctx, userKey)
+		assert.NoError(t, err)
+		assert.Len(t, sessions, 2)
+	}
+	// 剔除最久未访问会话
+	{
+		gToken := gtoken.NewDefaultToken(gtoken.Options{
+			MultiLogin:  true,
+			MaxSessions: 2,
+			EvictPolicy: gtoken.EvictPolicyLRU,
+			IdleTimeout: 5000,
+		})
+		token1, err := gToken.Generate(ctx, userKey, nil)
+		assert.NoError(t, err)
+		token2, err := gToken.Generate(ctx, userKey, nil)
+		assert.NoError(t, err)
+		time.Sleep(600 * time.Millisecond)
+		_, err = gToken.Validate(ctx, token1)
+		assert.NoError(t, err)
+		token3, err := gToken.Generate(ctx, userKey, nil)
+		assert.NoError(t, err)
+
+		_, err = gToken.Validate(ctx, token1)
+		assert.NoError(t, err)
+		_, err = gToken.Validate(ctx, token2)
+		assert.True(t, gtoken.IsKickedOut(err))
+		_, err = gToken.Validate(ctx, token3)
+		assert.NoError(t, err)
+	}
+	// 拒绝新登录
+	{
+		gToken := gtoken.NewDefaultToken(gtoken.Options{
+			MultiLogin:  true,
+			MaxSessions: 1,
+			EvictPolicy: gtoken.EvictPolicyReject,
+		})
+		token1, err := gToken.Generate(ctx, userKey, nil)
+		assert.NoError(t, err)
+		_, err = gToken.Generate(ctx, userKey, nil)
+		assert.Error(t, err)
+		assert.Equal(t, gtoken.CodeSessionLimit.Code(), gerror.Code(err).Code())
+		_, err = gToken.Validate(ctx, token1)
+		assert.NoError(t, err)
+	}
+	// 并发登录不超过会话数限制
+	for _, policy := range []int8{gtoken.EvictPolicyOldest, gtoken.EvictPolicyReject} {
+		gToken := gtoken.NewDefaultToken(gtoken.Options{
+			CacheMode:   gtoken.CacheModeFile,
+			CachePreKey: "GTokenMaxSessions:",
+			MultiLogin:  true,
+			MaxSessions: 2,
+			EvictPolicy: policy,
+		})
+		var (
+			wg      sync.WaitGroup
+			start   = make(chan struct{})
+			success atomic.Int32
+		)
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				if _, err := gToken.Generate(ctx, "concurrentUser", nil); err == nil {
+					success.Add(1)
+				}
+			}()
+		}
+		close(start)
+		wg.Wait()
+		sessions, err := gToken.Sessions(ctx, "concurrentUser")
+		assert.NoError(t, err)
+		assert.Len(t, sessions, 2)
+		if policy == gtoken.EvictPolicyReject {
+			assert.Equal(t, int32(2), success.Load())
+		}
+		assert.NoError(t, gToken.Destroy(ctx, "concurrentUser"))
+	}
+}
+
+func TestUpdateData(t *testing.T) {
+	var (
+		ctx     = gctx.New()
+		userKey = "testUser"
+	)
+	for _, cacheMode := range []int8{gtoken.CacheModeCache, gtoken.CacheModeFile} {
+		gToken := gtoken.NewDefaultToken(gtoken.Options{
+			CacheMode:   cacheMode,
+			CachePreKey: "GTokenUpdate:",
+			MultiLogin:  true,
+		})
+		token1, err := gToken.Generate(ctx, userKey, g.Map{"role": "user"})
+		assert.NoError(t, err)
+		token2, err := gToken.Generate(ctx, userKey, g.Map{"role": "user"})
+		assert.NoError(t, err)
+
+		// 更新全部会话数据，token不变
+		err = gToken.UpdateData(ctx, userKey, g.Map{"role": "admin"})
+		assert.NoError(t, err)
+		_, data, err := gToken.ParseToken(ctx, token1)
+		assert.NoError(t, err)
+		assert.Equal(t, g.Map{"role": "admin"}, data)
+		_, data, err = gToken.ParseToken(ctx, token2)
+		assert.NoError(t, err)
+		assert.Equal(t, g.Map{"role": "admin"}, data)
+
+		// 更新单个会话数据
+		sessions, err := gToken.Sessions(ctx, userKey)
+		assert.NoError(t, err)
+		err = gToken.UpdateSessionData(ctx, userKey, sessions[0].SessionId, g.Map{"role": "guest"})
+		assert.NoError(t, err)
+		_, data, err = gToken.ParseToken(ctx, token1)
+		assert.NoError(t, err)
+		assert.Equal(t, g.Map{"role": "guest"}, data)
+		_, data, err = gToken.ParseToken(ctx, token2)
+		assert.NoError(t, err)
+		assert.Equal(t, g.Map{"role": "admin"}, data)
+
+		err = gToken.UpdateSessionData(ctx, userKey, "notExist", g.Map{"role": "guest"})
+		assert.Error(t, err)
+		assert.NoError(t, gToken.Destroy(ctx, userKey))
+		err = gToken.UpdateData(ctx, userKey, g.Map{"role": "guest"})
+		assert.Error(t, err)
+	}
+}
+
+func TestReissueToken(t *testing.T) {
+	var (
+		ctx     = gctx.New()
+		userKey = "testUser"
+		oldKey  = []byte("koi29a83idakguqjq29asd9asd8a7jhq")
+	)
+	oldToken := gtoken.NewDefaultToken(gtoken.Options{EncryptKey: oldKey, StoreRawToken: true}).(*gtoken.GTokenV2)
+	gToken := gtoken.NewDefaultToken(gtoken.Options{
+		Timeout:       2000,
+		MaxRefresh:    300,
+		EncryptKid:    "k2",
+		DecryptKeys:   []gtoken.CodecKey{{Key: oldKey}},
+		ReissueToken:  true,
+		StoreRawToken: true,
+	}).(*gtoken.GTokenV2)
+	gToken.Cache = oldToken.Cache
+
+	s := g.Server(guid.S())
+	s.Group("/", func(group *ghttp.RouterGroup) {
+		group.Middleware(gtoken.NewDefaultMiddleware(gToken).Auth)
+		group.ALL("/user", func(r *ghttp.Request) {
+			r.Response.Write(r.GetCtxVar(gtoken.KeyUserKey).String())
+		})
+	})
+	s.SetPort(0)
+	s.SetDumpRouterMap(false)
+	assert.NoError(t, s.Start())
+	defer s.Shutdown()
+	url := fmt.Sprintf("http://127.0.0.1:%d/user", s.GetListenedPort())
+	request := func(token string) (renewToken string) {
+		resp, err := g.Client().SetHeader("Authorization", "Bearer "+token).Get(ctx, url)
+		assert.NoError(t, err)
+		defer resp.Close()
+		assert.Equal(t, userKey, resp.ReadAllString())
+		return resp.Header.Get(gtoken.HeaderRenewToken)
+	}
+
+	token, err := oldToken.Generate(ctx, userKey, nil)
+	assert.NoError(t, err)
+	// 未到续期时间，不重新签发
+	assert.Empty(t, request(token))
+	current, _, err := gToken.Get(ctx, userKey)
+	assert.NoError(t, err)
+	assert.Equal(t, token, current)
+
+	// 没有http请求时无法返回新token，仅续期不重新签发
+	time.Sleep(400 * time.Millisecond)
+	_, err = gToken.Validate(ctx, token)
+	assert.NoError(t, err)
+	current, _, err = gToken.Get(ctx, userKey)
+	assert.NoError(t, err)
+	assert.Equal(t, token, current)
+
+	// 续期时使用当前密钥重新签发，通过响应头返回
+	time.Sleep(400 * time.Millisecond)
+	newToken := request(token)
+	assert.NotEmpty(t, newToken)
+	assert.NotEqual(t, token, newToken)
+	current, _, err = gToken.Get(ctx, userKey)
+	assert.NoError(t, err)
+	assert.Equal(t, newToken, current)
+	_, err = oldToken.Validate(ctx, newToken)
+	assert.Error(t, err)
+
+	// 客户端未使用新token时，重新签发前的token续期后仍然有效
+	time.Sleep(400 * time.Millisecond)
+	_, err = gToken.Validate(ctx, token)
+	assert.NoError(t, err)
+	_, err = gToken.Validate(ctx, token)
+	assert.NoError(t, err)
+	_, err = gToken.Validate(ctx, newToken)
+	assert.NoError(t, err)
+
+	// 使用新token续期后旧token失效，新token不再重新签发
+	time.Sleep(400 * time.Millisecond)
+	assert.Empty(t, request(newToken))
+	current, _, err = gToken.Get(ctx, userKey)
+	assert.NoError(t, err)
+	assert.Equal(t, newToken, current)
+	_, err = gToken.Validate(ctx, token)
+	assert.Error(t, err)
+}
+
+func TestTokenHash(t *testing.T) {
+	var (
+		ctx     = gctx.New()
+		userKey = "testUser"
+	)
+	gToken := gtoken.NewDefaultToken(gtoken.Options{TokenHashKey: []byte("hash-key")}).(*gtoken.GTokenV2)
+	token, err := gToken.Generate(ctx, userKey, nil)
+	assert.NoError(t, err)
+	sessions, err := gToken.Sessions(ctx, userKey)
+	assert.NoError(t, err)
+	sessionKey := gtoken.CacheKeySession + userKey + ":" + sessions[0].SessionId
+
+	// 缓存不包含token原文
+	userCache, err := gToken.Cache.Get(ctx, sessionKey)
+	assert.NoError(t, err)
+	assert.Nil(t, userCache[gtoken.KeyToken])
+	assert.NotEmpty(t, userCache[gtoken.KeyTokenHash])
+	assert.NotContains(t, gjson.MustEncodeString(userCache), token)
+	_, err = gToken.Validate(ctx, token)
+	assert.NoError(t, err)
+
+	// 摘要密钥不一致
+	other := gtoken.NewDefaultToken(gtoken.Options{TokenHashKey: []byte("other-key")}).(*gtoken.GTokenV2)
+	other.Cache = gToken.Cache
+	_, err = other.Validate(ctx, token)
+	assert.Error(t, err)
+
+	// 升级前仅缓存token原文的会话仍然有效，更新会话时替换为摘要
+	delete(userCache, gtoken.KeyTokenHash)
+	userCache[gtoken.KeyToken] = token
+	assert.NoError(t, gToken.Cache.Set(ctx, sessionKey, userCache))
+	_, err = gToken.Validate(ctx, token)
+	assert.NoError(t, err)
+	_, err = gToken.Validate(ctx, token+"1")
+	assert.Error(t, err)
+	assert.NoError(t, gToken.UpdateSessionData(ctx, userKey, sessions[0].SessionId, "data"))
+	userCache, err = gToken.Cache.Get(ctx, sessionKey)
+	assert.NoError(t, err)
+	assert.Nil(t, userCache[gtoken.KeyToken])
+	assert.NotEmpty(t, userCache[gtoken.KeyTokenHash])
+	_, err = gToken.Validate(ctx, token)
+	assert.NoError(t, err)
+
+	// 未配置摘要密钥时由EncryptKey派生
+	oldKey := []byte("koi29a83idakguqjq29asd9asd8a7jhq")
+	derived := gtoken.NewDefaultToken(gtoken.Options{EncryptKey: oldKey}).(*gtoken.GTokenV2)
+	token, err = derived.Generate(ctx, userKey, nil)
+	assert.NoError(t, err)
+	sessions, err = derived.Sessions(ctx, userKey)
+	assert.NoError(t, err)
+	userCache, err = derived.Cache.Get(ctx, gtoken.CacheKeySession+userKey+":"+sessions[0].SessionId)
+	assert.NoError(t, err)
+	unkeyed := hmac.New(sha256.New, nil)
+	unkeyed.Write([]byte(token))
+	assert.NotEqual(t, hex.EncodeToString(unkeyed.Sum(nil)), userCache[gtoken.KeyTokenHash])
+	// 密钥轮换期间，旧密钥派生的摘要仍可验证
+	rotated := gtoken.NewDefaultToken(gtoken.Options{
+		EncryptKid:  "k2",
+		DecryptKeys: []gtoken.CodecKey{{Key: oldKey}},
+	}).(*gtoken.GTokenV2)
+	rotated.Cache = derived.Cache
+	_, err = rotated.Validate(ctx, token)
+	assert.NoError(t, err)
+
+	// 双token同样仅缓存摘要
+	pairToken := gtoken.NewDefaultToken(gtoken.Options{RefreshTimeout: 60000}).(*gtoken.GTokenV2)
+	pair, err := pairToken.GeneratePair(ctx, userKey, nil)
+	assert.NoError(t, err)
+	sessions, err = pairToken.Sessions(ctx, userKey)
+	assert.NoError(t, err)
+	userCache, err = pairToken.Cache.Get(ctx, gtoken.CacheKeySession+userKey+":"+sessions[0].SessionId)
+	assert.NoError(t, err)
+	assert.NotContains(t, gjson.MustEncodeString(userCache), pair.AccessToken)
+	assert.NotContains(t, gjson.MustEncodeString(userCache), pair.RefreshToken)
+	_, err = pairToken.Refresh(ctx, pair.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func TestCacheEncryptKey(t *testing.T) {
+	var (
+		ctx     = gctx.New()
+		userKey = "testUser"
+		data    = g.Map{"phone": "13800000000"}
+	)
+	gToken := gtoken.NewDefaultToken(gtoken.Options{
+		CacheEncryptKey: []byte("0123456789abcdef0123456789abcdef"),
+	}).(*gtoken.GTokenV2)
+	token, err := gToken.Generate(ctx, userKey, data)
+	assert.NoError(t, err)
+	_, data2, err := gToken.ParseToken(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, data, data2)
+
+	sessions, err := gToken.Sessions(ctx, userKey)
+	assert.NoError(t, err)
+	raw, err := gToken.Cache.(*gtoken.DefaultCache).Cache.Get(ctx, gtoken.DefaultCacheKey+gtoken.CacheKeySession+userKey+":"+sessions[0].SessionId)
+	assert.NoError(t, err)
+	assert.NotContains(t, raw.String(), "13800000000")
+
+	// 密钥长度错误或与EncryptKey、DecryptKeys、TokenHashKey相同
+	oldKey := []byte("koi29a83idakguqjq29asd9asd8a7jhq")
+	assert.Panics(t, func() {
+		gtoken.NewDefaultToken(gtoken.Options{CacheEncryptKey: []byte("123")})
+	})
+	assert.Panics(t, func() {
+		gtoken.NewDefaultToken(gtoken.Options{CacheEncryptKey: []byte(gtoken.DefaultEncryptKey)})
+	})
+	assert.Panics(t, func() {
+		gtoken.NewDefaultToken(gtoken.Options{
+			EncryptKid:      "k2",
+			DecryptKeys:     []gtoken.CodecKey{{Kid: "k1", Key: oldKey}},
+			CacheEncryptKey: oldKey,
+		})
+	})
+	assert.Panics(t, func() {
+		gtoken.NewDefaultToken(gtoken.Options{TokenHashKey: oldKey, CacheEncryptKey: oldKey})
+	})
+}
+
+// legacySession 模拟v2.0.x登录：token为userKey + 分隔符 + md5随机串，会话以userKey为缓存key
+func legacySession(t *testing.T, gToken *gtoken.GTokenV2, userKey string, data any, createTime int64) string {
+	randStr, err := gmd5.Encrypt(grand.Letters(10))
+	assert.NoError(t, err)
+	tokenByte, err := gaes.Encrypt([]byte(userKey+gtoken.DefaultTokenDelimiter+randStr), gToken.Options.EncryptKey)
+	assert.NoError(t, err)
+	token := gbase64.EncodeToString(tokenByte)
+	err = gToken.Cache.Set(gctx.New(), userKey, g.Map{
+		gtoken.KeyUserKey:    userKey,
+		gtoken.KeyToken:      token,
+		gtoken.KeyData:       data,
+		gtoken.KeyRefreshNum: 0,
+		gtoken.KeyCreateTime: createTime,
+	})
+	assert.NoError(t, err)
+	return token
+}
+
+func TestLegacySession(t *testing.T) {
+	ctx := gctx.New()
+	userKey := "testUser"
+	gToken := gtoken.NewDefaultToken(gtoken.Options{MultiLogin: true}).(*gtoken.GTokenV2)
+
+	// 升级前登录的会话首次访问时迁移
+	token := legacySession(t, gToken, userKey, "data", gtime.Now().TimestampMilli())
+	u, data, err := gToken.ParseToken(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, userKey, u)
+	assert.Equal(t, "data", data)
+	legacy, err := gToken.Cache.Get(ctx, userKey)
+	assert.NoError(t, err)
+	assert.Nil(t, legacy)
+	sessions, err := gToken.Sessions(ctx, userKey)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+	u, err = gToken.Validate(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, userKey, u)
+	assert.NoError(t, gToken.DestroyToken(ctx, token))
+	_, err = gToken.Validate(ctx, token)
+	assert.Error(t, err)
+
+	// 旧会话token已被替换
+	token = legacySession(t, gToken, userKey, nil, gtime.Now().TimestampMilli())
+	legacySession(t, gToken, userKey, nil, gtime.Now().TimestampMilli())
+	_, err = gToken.Validate(ctx, token)
+	assert.Error(t, err)
+
+	// 未迁移会话销毁
+	token = legacySession(t, gToken, userKey, nil, gtime.Now().TimestampMilli())
+	assert.NoError(t, gToken.Destroy(ctx, userKey))
+	_, err = gToken.Validate(ctx, token)
+	assert.Error(t, err)
+	token = legacySession(t, gToken, userKey, nil, gtime.Now().TimestampMilli())
+	assert.NoError(t, gToken.DestroyToken(ctx, token))
+	_, err = gToken.Validate(ctx, token)
+	assert.Error(t, err)
+
+	// 已超时会话
+	token = legacySession(t, gToken, userKey, nil, gtime.Now().TimestampMilli()-gtoken.DefaultTimeout-1000)
+	_, err = gToken.Validate(ctx, token)
+	assert.Equal(t, gtoken.CodeTokenExpired, gerror.Code(err))
+
+	// userKey与新缓存key相同时，不删除其他用户会话
+	token, err = gToken.Generate(ctx, "bob", nil)
+	assert.NoError(t, err)
+	sessions, err = gToken.Sessions(ctx, "bob")
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+	assert.NoError(t, gToken.Destroy(ctx, "user:bob"))
+	assert.NoError(t, gToken.Destroy(ctx, "session:bob:"+sessions[0].SessionId))
+	single := gtoken.NewDefaultToken(gtoken.Options{}).(*gtoken.GTokenV2)
+	_, err = single.Generate(ctx, "user:bob", nil)
+	assert.NoError(t, err)
+	sessions, err = gToken.Sessions(ctx, "bob")
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+	_, err = gToken.Validate(ctx, token)
+	assert.NoError(t, err)
+	assert.NoError(t, gToken.Destroy(ctx, "bob"))
+	_, err = gToken.Validate(ctx, token)
+	assert.Error(t, err)
+
+	// 拒绝旧格式token
+	disabled := gtoken.NewDefaultToken(gtoken.Options{DisableLegacy: true}).(*gtoken.GTokenV2)
+	token = legacySession(t, disabled, userKey, nil, gtime.Now().TimestampMilli())
+	_, err = disabled.Validate(ctx, token)
+	assert.Error(t, err)
+}